@@ -0,0 +1,131 @@
+// Command reconcile recomputes every user's balance from the starting grant,
+// coin_transactions, orders and refunds and prints the users whose cached
+// users.coin or ledger balance has drifted as a JSON report. With -fix it posts
+// ledger adjustment entries for the drifted balances after confirmation; balances
+// that would have to go negative are listed under "negative" instead. With
+// -rebuild it instead rewrites users.coin from the ledger postings, after
+// confirmation, and reports how many cached balances changed.
+//
+// Exit status: 0 without drift, 1 when drift was found (fixed or not), 2 on error.
+package main
+
+import (
+	"avito_test/internal/config"
+	"avito_test/internal/repository"
+	"avito_test/internal/worker"
+	"avito_test/pkg/logger"
+	"avito_test/pkg/storage/postgres"
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	exitOK    = 0
+	exitDrift = 1
+	exitError = 2
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	fix := flag.Bool("fix", false, "post adjustment entries for drifted balances")
+	rebuild := flag.Bool("rebuild", false, "rebuild users.coin from the ledger instead of reconciling")
+	yes := flag.Bool("yes", false, "do not ask for confirmation before fixing or rebuilding")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("can't load config: %v", err)
+		return exitError
+	}
+	// Keep stdout for the report.
+	cfg.Logger.MinLevel = int8(logger.WarnLevel)
+
+	appLogger := logger.NewApiLogger(cfg)
+	if err = appLogger.InitLogger(); err != nil {
+		log.Printf("can't init logger: %v", err)
+		return exitError
+	}
+
+	db, err := postgres.InitPsqlDB(cfg, appLogger)
+	if err != nil {
+		log.Printf("can't connect to postgres: %v", err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	ledger := repository.NewLedger(db)
+
+	if *rebuild {
+		return rebuildBalances(ctx, ledger, *yes)
+	}
+
+	reconciler := worker.NewReconciler(ledger, 0, appLogger)
+
+	report, err := reconciler.Check(ctx)
+	if err != nil {
+		log.Printf("reconciliation failed: %v", err)
+		return exitError
+	}
+
+	if *fix && len(report.Drift) > 0 && (*yes || confirm(fmt.Sprintf("post adjustment entries for %d drifted balances?", len(report.Drift)))) {
+		if err = reconciler.Correct(ctx, report); err != nil {
+			log.Printf("correction failed: %v", err)
+			return exitError
+		}
+	}
+
+	if err = writeReport(report); err != nil {
+		log.Printf("can't write report: %v", err)
+		return exitError
+	}
+
+	if len(report.Drift) > 0 {
+		return exitDrift
+	}
+	return exitOK
+}
+
+type rebuildReport struct {
+	RebuiltAt time.Time `json:"rebuiltAt"`
+	Rebuilt   int64     `json:"rebuilt"`
+}
+
+func rebuildBalances(ctx context.Context, ledger repository.Ledger, yes bool) int {
+	if !yes && !confirm("rebuild every cached balance from the ledger?") {
+		return exitOK
+	}
+
+	rebuilt, err := ledger.RebuildBalances(ctx)
+	if err != nil {
+		log.Printf("rebuild failed: %v", err)
+		return exitError
+	}
+
+	if err = writeReport(rebuildReport{RebuiltAt: time.Now().UTC(), Rebuilt: rebuilt}); err != nil {
+		log.Printf("can't write report: %v", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func writeReport(report any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "y")
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -29,18 +31,65 @@ type Config struct {
 	Auth struct {
 		Secret string `json:"secret"`
 	} `json:"auth"`
+
+	Token struct {
+		AccessTTL      time.Duration `json:"accessTtl"`
+		RefreshTTL     time.Duration `json:"refreshTtl"`
+		KeysDir        string        `json:"keysDir"`
+		ActiveKID      string        `json:"activeKid"`
+		RotationWindow time.Duration `json:"rotationWindow"`
+		SweepInterval  time.Duration `json:"sweepInterval"`
+	} `json:"token"`
+
+	Password struct {
+		Algorithm         string `json:"algorithm"`
+		BcryptCost        int    `json:"bcryptCost"`
+		Argon2Memory      uint32 `json:"argon2Memory"`
+		Argon2Iterations  uint32 `json:"argon2Iterations"`
+		Argon2Parallelism uint8  `json:"argon2Parallelism"`
+	} `json:"password"`
+
+	Idempotency struct {
+		TTL           time.Duration `json:"ttl"`
+		SweepInterval time.Duration `json:"sweepInterval"`
+	} `json:"idempotency"`
+
+	History struct {
+		InfoLimit int `json:"infoLimit"`
+	} `json:"history"`
+
+	// Admin.Username is granted the admin role at startup, so a fresh
+	// installation has someone who can grant roles to others.
+	Admin struct {
+		Username string `json:"username"`
+	} `json:"admin"`
+
+	Reconcile struct {
+		Interval time.Duration `json:"interval"`
+	} `json:"reconcile"`
+
+	Returns struct {
+		Window time.Duration `json:"window"`
+	} `json:"returns"`
+
+	PaymentRequests struct {
+		TTL time.Duration `json:"ttl"`
+	} `json:"paymentRequests"`
 }
 
 func LoadConfig() (*Config, error) {
 	requiredEnvVars := []string{
 		"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB",
-		"SERVER_PORT", "JWT_SECRET_KEY",
+		"SERVER_PORT",
 	}
 	for _, env := range requiredEnvVars {
 		if os.Getenv(env) == "" {
 			return nil, fmt.Errorf("%s is not set in the environment", env)
 		}
 	}
+	if os.Getenv("JWT_SECRET_KEY") == "" && os.Getenv("JWT_KEYS_DIR") == "" {
+		return nil, fmt.Errorf("either JWT_SECRET_KEY or JWT_KEYS_DIR must be set in the environment")
+	}
 
 	cfg := &Config{
 		ServiceName: "Avito Test",
@@ -76,7 +125,90 @@ func LoadConfig() (*Config, error) {
 		}{
 			Secret: os.Getenv("JWT_SECRET_KEY"),
 		},
+		Token: struct {
+			AccessTTL      time.Duration `json:"accessTtl"`
+			RefreshTTL     time.Duration `json:"refreshTtl"`
+			KeysDir        string        `json:"keysDir"`
+			ActiveKID      string        `json:"activeKid"`
+			RotationWindow time.Duration `json:"rotationWindow"`
+			SweepInterval  time.Duration `json:"sweepInterval"`
+		}{
+			AccessTTL:      getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTTL:     getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			KeysDir:        os.Getenv("JWT_KEYS_DIR"),
+			ActiveKID:      os.Getenv("JWT_ACTIVE_KID"),
+			RotationWindow: getEnvDuration("JWT_ROTATION_WINDOW", 24*time.Hour),
+			SweepInterval:  getEnvDuration("TOKEN_SWEEP_INTERVAL", time.Hour),
+		},
+		Password: struct {
+			Algorithm         string `json:"algorithm"`
+			BcryptCost        int    `json:"bcryptCost"`
+			Argon2Memory      uint32 `json:"argon2Memory"`
+			Argon2Iterations  uint32 `json:"argon2Iterations"`
+			Argon2Parallelism uint8  `json:"argon2Parallelism"`
+		}{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			BcryptCost:        getEnvInt("PASSWORD_BCRYPT_COST", 10),
+			Argon2Memory:      uint32(getEnvInt("PASSWORD_ARGON2_MEMORY_KIB", 19*1024)),
+			Argon2Iterations:  uint32(getEnvInt("PASSWORD_ARGON2_ITERATIONS", 2)),
+			Argon2Parallelism: uint8(getEnvInt("PASSWORD_ARGON2_PARALLELISM", 1)),
+		},
+		Idempotency: struct {
+			TTL           time.Duration `json:"ttl"`
+			SweepInterval time.Duration `json:"sweepInterval"`
+		}{
+			TTL:           getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			SweepInterval: getEnvDuration("IDEMPOTENCY_SWEEP_INTERVAL", time.Hour),
+		},
+		History: struct {
+			InfoLimit int `json:"infoLimit"`
+		}{
+			InfoLimit: getEnvInt("INFO_HISTORY_LIMIT", 100),
+		},
+		Admin: struct {
+			Username string `json:"username"`
+		}{
+			Username: os.Getenv("ADMIN_USERNAME"),
+		},
+		Reconcile: struct {
+			Interval time.Duration `json:"interval"`
+		}{
+			Interval: getEnvDuration("RECONCILE_INTERVAL", 0),
+		},
+		Returns: struct {
+			Window time.Duration `json:"window"`
+		}{
+			Window: getEnvDuration("RETURN_WINDOW", 14*24*time.Hour),
+		},
+		PaymentRequests: struct {
+			TTL time.Duration `json:"ttl"`
+		}{
+			TTL: getEnvDuration("PAYMENT_REQUEST_TTL", 7*24*time.Hour),
+		},
 	}
 
 	return cfg, nil
 }
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"avito_test/internal/domain"
+	"avito_test/internal/jwt"
 	"context"
 	"github.com/gofiber/fiber/v3"
 	"github.com/pkg/errors"
@@ -9,6 +10,10 @@ import (
 
 type AuthService interface {
 	Auth(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error)
+	Register(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error)
+	Login(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error)
+	Refresh(ctx context.Context, req domain.RefreshRequest) (*domain.AuthResponse, error)
+	Logout(ctx context.Context, userIDStr string, claims jwt.Claims, req domain.LogoutRequest) error
 }
 
 type Auth struct {
@@ -24,11 +29,11 @@ func NewAuth(service AuthService) Auth {
 // Auth
 // @Tags auth
 // @Summary Авторизация
-// @Description Авторизация пользователя
+// @Description Вход пользователя, при первом входе пользователь регистрируется
 // @Accept json
 // @Produce json
 // @Param body domain.AuthRequest true "Данные для авторизации"
-// @Success 201 {object} domain.AuthResponse
+// @Success 200 {object} domain.AuthResponse
 // @Failure 400 {object} domain.ErrorResponse
 // @Failure 401 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
@@ -41,15 +46,147 @@ func (a Auth) Auth() fiber.Handler {
 		}
 
 		res, err := a.service.Auth(ctx.Context(), req)
+		return authResponse(ctx, fiber.StatusOK, res, err)
+	}
+}
+
+// Register
+// @Tags auth
+// @Summary Регистрация
+// @Description Регистрация нового пользователя
+// @Accept json
+// @Produce json
+// @Param body domain.AuthRequest true "Данные для регистрации"
+// @Success 201 {object} domain.AuthResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /register [POST]
+func (a Auth) Register() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.AuthRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+		}
+
+		res, err := a.service.Register(ctx.Context(), req)
+		return authResponse(ctx, fiber.StatusCreated, res, err)
+	}
+}
+
+// Login
+// @Tags auth
+// @Summary Вход
+// @Description Вход существующего пользователя
+// @Accept json
+// @Produce json
+// @Param body domain.AuthRequest true "Данные для входа"
+// @Success 200 {object} domain.AuthResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /login [POST]
+func (a Auth) Login() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.AuthRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+		}
+
+		res, err := a.service.Login(ctx.Context(), req)
+		return authResponse(ctx, fiber.StatusOK, res, err)
+	}
+}
+
+// Refresh
+// @Tags auth
+// @Summary Обновление токенов
+// @Description Обмен refresh-токена на новую пару токенов
+// @Accept json
+// @Produce json
+// @Param body domain.RefreshRequest true "Refresh-токен"
+// @Success 200 {object} domain.AuthResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /auth/refresh [POST]
+func (a Auth) Refresh() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.RefreshRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+		}
+
+		res, err := a.service.Refresh(ctx.Context(), req)
+		if errors.Is(err, domain.ErrInvalidInput) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "refresh token is required"})
+		}
+		return authResponse(ctx, fiber.StatusOK, res, err)
+	}
+}
+
+// Logout
+// @Tags auth
+// @Summary Выход
+// @Description Отзыв access-токена и семейства refresh-токенов
+// @Accept json
+// @Produce json
+// @Param body domain.LogoutRequest false "Refresh-токен"
+// @Success 204 "Токены отозваны"
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /auth/logout [POST]
+func (a Auth) Logout() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		claims, ok := ctx.Locals("claims").(jwt.Claims)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
+		}
+
+		var req domain.LogoutRequest
+		if len(ctx.Body()) > 0 {
+			if err := ctx.Bind().Body(&req); err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+			}
+		}
+
+		err := a.service.Logout(ctx.Context(), userIDStr, claims, req)
 		switch {
 		case errors.Is(err, domain.ErrInvalidCredentials):
 			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid credentials"})
-		case errors.Is(err, domain.ErrUnauthorized):
-			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
+		case errors.Is(err, domain.ErrInvalidRefreshToken):
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid refresh token"})
 		case err != nil:
 			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
 		default:
-			return ctx.Status(fiber.StatusOK).JSON(res)
+			return ctx.SendStatus(fiber.StatusNoContent)
 		}
 	}
 }
+
+func authResponse(ctx fiber.Ctx, successStatus int, res *domain.AuthResponse, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "username and password are required"})
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid credentials"})
+	case errors.Is(err, domain.ErrInvalidRefreshToken):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid refresh token"})
+	case errors.Is(err, domain.ErrRefreshTokenReused):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "refresh token reuse detected"})
+	case errors.Is(err, domain.ErrUserAlreadyExists):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{Errors: "user already exists"})
+	case errors.Is(err, domain.ErrUnauthorized):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
+	case err != nil:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
+	default:
+		return ctx.Status(successStatus).JSON(res)
+	}
+}
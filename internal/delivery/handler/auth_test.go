@@ -2,6 +2,7 @@ package handler
 
 import (
 	"avito_test/internal/domain"
+	"avito_test/internal/jwt"
 	"context"
 	"errors"
 	"github.com/gofiber/fiber/v3"
@@ -22,6 +23,26 @@ func (m *MockAuthService) Auth(ctx context.Context, req domain.AuthRequest) (*do
 	return args.Get(0).(*domain.AuthResponse), args.Error(1)
 }
 
+func (m *MockAuthService) Register(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Login(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Refresh(ctx context.Context, req domain.RefreshRequest) (*domain.AuthResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, userIDStr string, claims jwt.Claims, req domain.LogoutRequest) error {
+	args := m.Called(ctx, userIDStr, claims, req)
+	return args.Error(0)
+}
+
 func TestAuthHandler(t *testing.T) {
 	mockService := new(MockAuthService)
 
@@ -58,7 +79,7 @@ func TestAuthHandler(t *testing.T) {
 					Password: "invalidPass",
 				}).Return((*domain.AuthResponse)(nil), domain.ErrInvalidCredentials)
 			},
-			expectedStatus: fiber.StatusBadRequest,
+			expectedStatus: fiber.StatusUnauthorized,
 			expectedBody:   `{"errors":"invalid credentials"}`,
 		},
 		{
@@ -104,3 +125,269 @@ func TestAuthHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterHandler(t *testing.T) {
+	mockService := new(MockAuthService)
+
+	handler := NewAuth(mockService)
+
+	app := fiber.New()
+	app.Post("/register", handler.Register())
+
+	tests := []struct {
+		name           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: `{"username": "newUser", "password": "NewPass123!"}`,
+			mock: func() {
+				mockService.On("Register", mock.Anything, domain.AuthRequest{
+					Username: "newUser",
+					Password: "NewPass123!",
+				}).Return(&domain.AuthResponse{Token: "12345"}, nil)
+			},
+			expectedStatus: fiber.StatusCreated,
+			expectedBody:   `{"token":"12345"}`,
+		},
+		{
+			name: "Already Exists",
+			body: `{"username": "takenUser", "password": "TakenPass123!"}`,
+			mock: func() {
+				mockService.On("Register", mock.Anything, domain.AuthRequest{
+					Username: "takenUser",
+					Password: "TakenPass123!",
+				}).Return((*domain.AuthResponse)(nil), domain.ErrUserAlreadyExists)
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"user already exists"}`,
+		},
+		{
+			name: "Invalid Input",
+			body: `{"username": "", "password": ""}`,
+			mock: func() {
+				mockService.On("Register", mock.Anything, domain.AuthRequest{}).
+					Return((*domain.AuthResponse)(nil), domain.ErrInvalidInput)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"username and password are required"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			req := httptest.NewRequest("POST", "/register", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	mockService := new(MockAuthService)
+
+	handler := NewAuth(mockService)
+
+	app := fiber.New()
+	app.Post("/login", handler.Login())
+
+	tests := []struct {
+		name           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: `{"username": "validUser", "password": "ValidPass123!"}`,
+			mock: func() {
+				mockService.On("Login", mock.Anything, domain.AuthRequest{
+					Username: "validUser",
+					Password: "ValidPass123!",
+				}).Return(&domain.AuthResponse{Token: "12345"}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"token":"12345"}`,
+		},
+		{
+			name: "Wrong Password",
+			body: `{"username": "validUser", "password": "wrongPass"}`,
+			mock: func() {
+				mockService.On("Login", mock.Anything, domain.AuthRequest{
+					Username: "validUser",
+					Password: "wrongPass",
+				}).Return((*domain.AuthResponse)(nil), domain.ErrInvalidCredentials)
+			},
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedBody:   `{"errors":"invalid credentials"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	mockService := new(MockAuthService)
+
+	handler := NewAuth(mockService)
+
+	app := fiber.New()
+	app.Post("/auth/refresh", handler.Refresh())
+
+	tests := []struct {
+		name           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			body: `{"refreshToken": "valid"}`,
+			mock: func() {
+				mockService.On("Refresh", mock.Anything, domain.RefreshRequest{RefreshToken: "valid"}).
+					Return(&domain.AuthResponse{Token: "12345", RefreshToken: "next", ExpiresIn: 900}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"token":"12345","refreshToken":"next","expiresIn":900}`,
+		},
+		{
+			name: "Invalid Token",
+			body: `{"refreshToken": "unknown"}`,
+			mock: func() {
+				mockService.On("Refresh", mock.Anything, domain.RefreshRequest{RefreshToken: "unknown"}).
+					Return((*domain.AuthResponse)(nil), domain.ErrInvalidRefreshToken)
+			},
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedBody:   `{"errors":"invalid refresh token"}`,
+		},
+		{
+			name: "Reused Token",
+			body: `{"refreshToken": "rotated"}`,
+			mock: func() {
+				mockService.On("Refresh", mock.Anything, domain.RefreshRequest{RefreshToken: "rotated"}).
+					Return((*domain.AuthResponse)(nil), domain.ErrRefreshTokenReused)
+			},
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedBody:   `{"errors":"refresh token reuse detected"}`,
+		},
+		{
+			name: "Missing Token",
+			body: `{}`,
+			mock: func() {
+				mockService.On("Refresh", mock.Anything, domain.RefreshRequest{}).
+					Return((*domain.AuthResponse)(nil), domain.ErrInvalidInput)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"refresh token is required"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			req := httptest.NewRequest("POST", "/auth/refresh", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLogoutHandler(t *testing.T) {
+	mockService := new(MockAuthService)
+
+	handler := NewAuth(mockService)
+
+	claims := jwt.Claims{ID: "user-id", Username: "validUser", TokenID: "jti"}
+
+	app := fiber.New()
+	app.Post("/auth/logout", handler.Logout(), func(ctx fiber.Ctx) error {
+		ctx.Locals("id", claims.ID)
+		ctx.Locals("claims", claims)
+		return ctx.Next()
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		mock           func()
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			body: `{"refreshToken": "valid"}`,
+			mock: func() {
+				mockService.On("Logout", mock.Anything, claims.ID, claims, domain.LogoutRequest{RefreshToken: "valid"}).
+					Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name: "Without Refresh Token",
+			body: ``,
+			mock: func() {
+				mockService.On("Logout", mock.Anything, claims.ID, claims, domain.LogoutRequest{}).
+					Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name: "Foreign Refresh Token",
+			body: `{"refreshToken": "foreign"}`,
+			mock: func() {
+				mockService.On("Logout", mock.Anything, claims.ID, claims, domain.LogoutRequest{RefreshToken: "foreign"}).
+					Return(domain.ErrInvalidRefreshToken)
+			},
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			req := httptest.NewRequest("POST", "/auth/logout", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strings"
+)
+
+const catalogCacheControl = "public, max-age=60"
+
+type CatalogService interface {
+	List(ctx context.Context) (*domain.CatalogResponse, error)
+	Get(ctx context.Context, itemType string) (*domain.CatalogItem, error)
+	Create(ctx context.Context, adminIDStr string, req domain.CreateItemRequest) (*domain.CatalogItem, error)
+	Update(ctx context.Context, adminIDStr string, itemType string, req domain.UpdateItemRequest) (*domain.CatalogItem, error)
+	Retire(ctx context.Context, adminIDStr string, itemType string) (*domain.CatalogItem, error)
+	Restock(ctx context.Context, adminIDStr string, itemType string, req domain.RestockRequest) (*domain.CatalogItem, error)
+	CreateVariant(ctx context.Context, adminIDStr string, itemType string, req domain.CreateVariantRequest) (*domain.CatalogItem, error)
+	PriceHistory(ctx context.Context, itemType string) (*domain.PriceHistoryResponse, error)
+}
+
+type Catalog struct {
+	service CatalogService
+}
+
+func NewCatalog(service CatalogService) Catalog {
+	return Catalog{
+		service: service,
+	}
+}
+
+// List
+// @Tags catalog
+// @Summary Каталог мерча
+// @Description Список предметов с ценой, описанием, категорией и доступностью. Поддерживает If-None-Match
+// @Produce json
+// @Param If-None-Match header string false "ETag ранее полученного каталога"
+// @Success 200 {object} domain.CatalogResponse "Каталог"
+// @Success 304 "Каталог не изменился"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /items [GET]
+func (c Catalog) List() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		catalog, err := c.service.List(ctx.Context())
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+		}
+
+		return sendWithETag(ctx, catalog)
+	}
+}
+
+// Get
+// @Tags catalog
+// @Summary Предмет каталога
+// @Description Информация о предмете по его типу. Поддерживает If-None-Match
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Param If-None-Match header string false "ETag ранее полученного предмета"
+// @Success 200 {object} domain.CatalogItem "Предмет"
+// @Success 304 "Предмет не изменился"
+// @Failure 404 {object} domain.ErrorResponse "Предмет не найден"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /items/{type} [GET]
+func (c Catalog) Get() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		item, err := c.service.Get(ctx.Context(), ctx.Params("type"))
+		switch {
+		case errors.Is(err, domain.ErrItemNotFound):
+			return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "item not found", Code: domain.CodeItemNotFound})
+		case err != nil:
+			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+		}
+
+		return sendWithETag(ctx, item)
+	}
+}
+
+// Create
+// @Tags admin
+// @Summary Добавление предмета
+// @Description Добавление нового предмета в каталог администратором
+// @Accept json
+// @Produce json
+// @Param body body domain.CreateItemRequest true "Предмет"
+// @Success 201 {object} domain.CatalogItem "Созданный предмет"
+// @Failure 400 {object} domain.ErrorResponse "Некорректные данные предмета"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 409 {object} domain.ErrorResponse "Предмет с таким типом уже существует"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items [POST]
+func (c Catalog) Create() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.CreateItemRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		item, err := c.service.Create(ctx.Context(), adminIDStr, req)
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusCreated).JSON(item)
+	}
+}
+
+// Update
+// @Tags admin
+// @Summary Изменение предмета
+// @Description Изменение цены, описания, категории или доступности предмета. Передаются только изменяемые поля
+// @Accept json
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Param body body domain.UpdateItemRequest true "Изменения"
+// @Success 200 {object} domain.CatalogItem "Обновлённый предмет"
+// @Failure 400 {object} domain.ErrorResponse "Некорректные данные предмета"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Предмет не найден"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items/{type} [PATCH]
+func (c Catalog) Update() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.UpdateItemRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		item, err := c.service.Update(ctx.Context(), adminIDStr, ctx.Params("type"), req)
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(item)
+	}
+}
+
+// Retire
+// @Tags admin
+// @Summary Снятие предмета с продажи
+// @Description Предмет больше нельзя купить, но он остаётся в инвентарях купивших его пользователей
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Success 200 {object} domain.CatalogItem "Снятый с продажи предмет"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Предмет не найден"
+// @Failure 410 {object} domain.ErrorResponse "Предмет уже снят с продажи"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items/{type} [DELETE]
+func (c Catalog) Retire() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		item, err := c.service.Retire(ctx.Context(), adminIDStr, ctx.Params("type"))
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(item)
+	}
+}
+
+// Restock
+// @Tags admin
+// @Summary Пополнение запаса
+// @Description Увеличение запаса предмета или одного из его вариантов. Неограниченный запас становится ограниченным
+// @Accept json
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Param body body domain.RestockRequest true "Количество"
+// @Success 200 {object} domain.CatalogItem "Предмет с новым запасом"
+// @Failure 400 {object} domain.ErrorResponse "Некорректное количество"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Предмет или вариант не найден"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items/{type}/restock [POST]
+func (c Catalog) Restock() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.RestockRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		item, err := c.service.Restock(ctx.Context(), adminIDStr, ctx.Params("type"), req)
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(item)
+	}
+}
+
+// CreateVariant
+// @Tags admin
+// @Summary Добавление варианта
+// @Description Добавление варианта предмета (размер, цвет) со своим SKU, ценой и запасом. После этого вариант нужно выбирать при покупке
+// @Accept json
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Param body body domain.CreateVariantRequest true "Вариант"
+// @Success 201 {object} domain.CatalogItem "Предмет со всеми вариантами"
+// @Failure 400 {object} domain.ErrorResponse "Некорректные данные варианта"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Предмет не найден"
+// @Failure 409 {object} domain.ErrorResponse "Вариант с таким SKU уже существует"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items/{type}/variants [POST]
+func (c Catalog) CreateVariant() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.CreateVariantRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		item, err := c.service.CreateVariant(ctx.Context(), adminIDStr, ctx.Params("type"), req)
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusCreated).JSON(item)
+	}
+}
+
+// PriceHistory
+// @Tags admin
+// @Summary История цен предмета
+// @Description Все изменения предмета, начиная с последнего
+// @Produce json
+// @Param type path string true "Тип предмета"
+// @Success 200 {object} domain.PriceHistoryResponse "История изменений"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Предмет не найден"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/items/{type}/prices [GET]
+func (c Catalog) PriceHistory() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		history, err := c.service.PriceHistory(ctx.Context(), ctx.Params("type"))
+		if err != nil {
+			return catalogError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(history)
+	}
+}
+
+func catalogError(ctx fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized", Code: domain.CodeUnauthorized})
+	case errors.Is(err, domain.ErrInvalidItem):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{
+			Errors: "item needs a valid type, a price and stock of 0-1000000 and a description of up to 500 characters",
+			Code:   domain.CodeInvalidItem,
+		})
+	case errors.Is(err, domain.ErrInvalidAmount):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "quantity must be 1-1000000", Code: domain.CodeInvalidAmount})
+	case errors.Is(err, domain.ErrItemNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "item not found", Code: domain.CodeItemNotFound})
+	case errors.Is(err, domain.ErrVariantNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "variant not found", Code: domain.CodeVariantNotFound})
+	case errors.Is(err, domain.ErrItemAlreadyExists):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{Errors: "item already exists", Code: domain.CodeItemAlreadyExists})
+	case errors.Is(err, domain.ErrVariantAlreadyExists):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{Errors: "variant already exists", Code: domain.CodeVariantAlreadyExists})
+	case errors.Is(err, domain.ErrItemRetired):
+		return ctx.Status(fiber.StatusGone).JSON(domain.ErrorResponse{Errors: "item is retired", Code: domain.CodeItemRetired})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+	}
+}
+
+// sendWithETag tags the JSON body with a strong ETag derived from its content
+// and answers 304 Not Modified when the client already holds that version.
+func sendWithETag(ctx fiber.Ctx, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderCacheControl, catalogCacheControl)
+
+	if etagMatches(ctx.Get(fiber.HeaderIfNoneMatch), etag) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return ctx.Status(fiber.StatusOK).Send(body)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockCatalogService struct {
+	mock.Mock
+}
+
+func (m *MockCatalogService) List(ctx context.Context) (*domain.CatalogResponse, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) Get(ctx context.Context, itemType string) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, itemType)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) Create(ctx context.Context, adminIDStr string, req domain.CreateItemRequest) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, adminIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) Update(
+	ctx context.Context,
+	adminIDStr string,
+	itemType string,
+	req domain.UpdateItemRequest,
+) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, adminIDStr, itemType, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) Retire(ctx context.Context, adminIDStr string, itemType string) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, adminIDStr, itemType)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) Restock(
+	ctx context.Context,
+	adminIDStr string,
+	itemType string,
+	req domain.RestockRequest,
+) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, adminIDStr, itemType, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) CreateVariant(
+	ctx context.Context,
+	adminIDStr string,
+	itemType string,
+	req domain.CreateVariantRequest,
+) (*domain.CatalogItem, error) {
+	args := m.Called(ctx, adminIDStr, itemType, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.CatalogItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCatalogService) PriceHistory(ctx context.Context, itemType string) (*domain.PriceHistoryResponse, error) {
+	args := m.Called(ctx, itemType)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PriceHistoryResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestCatalogHandler_List(t *testing.T) {
+	mockService := new(MockCatalogService)
+
+	handler := NewCatalog(mockService)
+
+	app := fiber.New()
+	app.Get("/items", handler.List())
+
+	catalog := &domain.CatalogResponse{Items: []domain.CatalogItem{
+		{Type: "cup", Price: 20, Description: "Кружка с логотипом", Category: "drinkware", Available: true},
+	}}
+	mockService.On("List", mock.Anything).Return(catalog, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/items", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"items":[{"type":"cup","price":20,"description":"Кружка с логотипом","category":"drinkware","available":true}]}`, string(body))
+
+	etag := resp.Header.Get(fiber.HeaderETag)
+	require.NotEmpty(t, etag)
+
+	tests := []struct {
+		name           string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{name: "Matching ETag", ifNoneMatch: etag, expectedStatus: fiber.StatusNotModified},
+		{name: "Weak Matching ETag", ifNoneMatch: `"stale", W/` + etag, expectedStatus: fiber.StatusNotModified},
+		{name: "Wildcard", ifNoneMatch: "*", expectedStatus: fiber.StatusNotModified},
+		{name: "Stale ETag", ifNoneMatch: `"stale"`, expectedStatus: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.Header.Set(fiber.HeaderIfNoneMatch, tt.ifNoneMatch)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			require.Equal(t, etag, resp.Header.Get(fiber.HeaderETag))
+		})
+	}
+}
+
+func TestCatalogHandler_Get(t *testing.T) {
+	mockService := new(MockCatalogService)
+
+	handler := NewCatalog(mockService)
+
+	app := fiber.New()
+	app.Get("/items/:type", handler.Get())
+
+	tests := []struct {
+		name           string
+		itemType       string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:     "Success",
+			itemType: "pen",
+			mock: func() {
+				mockService.On("Get", mock.Anything, "pen").
+					Return(&domain.CatalogItem{Type: "pen", Price: 10, Category: "stationery", Available: true}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"type":"pen","price":10,"description":"","category":"stationery","available":true}`,
+		},
+		{
+			name:     "Not Found",
+			itemType: "yacht",
+			mock: func() {
+				mockService.On("Get", mock.Anything, "yacht").Return(nil, domain.ErrItemNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"item not found","code":"item_not_found"}`,
+		},
+		{
+			name:     "Internal Server Error",
+			itemType: "cup",
+			mock: func() {
+				mockService.On("Get", mock.Anything, "cup").Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/items/"+tt.itemType, nil))
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
+
+func TestCatalogHandler_Admin(t *testing.T) {
+	mockService := new(MockCatalogService)
+
+	handler := NewCatalog(mockService)
+
+	const adminID = "0b9ce7a4-7c43-4c1e-9d8a-5c2f1f0e6a11"
+
+	app := fiber.New()
+	app.Use(func(ctx fiber.Ctx) error {
+		ctx.Locals("id", adminID)
+		return ctx.Next()
+	})
+	app.Post("/items", handler.Create())
+	app.Patch("/items/:type", handler.Update())
+	app.Delete("/items/:type", handler.Retire())
+	app.Post("/items/:type/restock", handler.Restock())
+	app.Post("/items/:type/variants", handler.CreateVariant())
+	app.Get("/items/:type/prices", handler.PriceHistory())
+
+	price := int64(25)
+	stock, threshold := 15, 5
+	variantPrice := int64(350)
+	retiredAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Create",
+			method: http.MethodPost,
+			path:   "/items",
+			body:   `{"type":"cap","price":30,"description":"Кепка","category":"apparel"}`,
+			mock: func() {
+				req := domain.CreateItemRequest{Type: "cap", Price: 30, Description: "Кепка", Category: "apparel"}
+				mockService.On("Create", mock.Anything, adminID, req).
+					Return(&domain.CatalogItem{Type: "cap", Price: 30, Description: "Кепка", Category: "apparel", Available: true}, nil).Once()
+			},
+			expectedStatus: fiber.StatusCreated,
+			expectedBody:   `{"type":"cap","price":30,"description":"Кепка","category":"apparel","available":true}`,
+		},
+		{
+			name:   "Create Duplicate",
+			method: http.MethodPost,
+			path:   "/items",
+			body:   `{"type":"cup","price":30}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, adminID, domain.CreateItemRequest{Type: "cup", Price: 30}).
+					Return(nil, domain.ErrItemAlreadyExists).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"item already exists","code":"item_already_exists"}`,
+		},
+		{
+			name:   "Create Invalid",
+			method: http.MethodPost,
+			path:   "/items",
+			body:   `{"type":"cap","price":-1}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, adminID, domain.CreateItemRequest{Type: "cap", Price: -1}).
+					Return(nil, domain.ErrInvalidItem).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody: `{"errors":"item needs a valid type, a price and stock of 0-1000000 and a description of up to 500 characters",` +
+				`"code":"invalid_item"}`,
+		},
+		{
+			name:   "Reprice",
+			method: http.MethodPatch,
+			path:   "/items/cup",
+			body:   `{"price":25}`,
+			mock: func() {
+				mockService.On("Update", mock.Anything, adminID, "cup", domain.UpdateItemRequest{Price: &price}).
+					Return(&domain.CatalogItem{Type: "cup", Price: 25, Category: "drinkware", Available: true}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"type":"cup","price":25,"description":"","category":"drinkware","available":true}`,
+		},
+		{
+			name:   "Reprice Retired",
+			method: http.MethodPatch,
+			path:   "/items/pen",
+			body:   `{"price":25}`,
+			mock: func() {
+				mockService.On("Update", mock.Anything, adminID, "pen", domain.UpdateItemRequest{Price: &price}).
+					Return(nil, domain.ErrItemRetired).Once()
+			},
+			expectedStatus: fiber.StatusGone,
+			expectedBody:   `{"errors":"item is retired","code":"item_retired"}`,
+		},
+		{
+			name:   "Retire",
+			method: http.MethodDelete,
+			path:   "/items/socks",
+			mock: func() {
+				mockService.On("Retire", mock.Anything, adminID, "socks").
+					Return(&domain.CatalogItem{Type: "socks", Price: 10, Category: "apparel", RetiredAt: &retiredAt}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"type":"socks","price":10,"description":"","category":"apparel","available":false,` +
+				`"retiredAt":"2025-03-01T12:00:00Z"}`,
+		},
+		{
+			name:   "Retire Not Found",
+			method: http.MethodDelete,
+			path:   "/items/yacht",
+			mock: func() {
+				mockService.On("Retire", mock.Anything, adminID, "yacht").Return(nil, domain.ErrItemNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"item not found","code":"item_not_found"}`,
+		},
+		{
+			name:   "Restock",
+			method: http.MethodPost,
+			path:   "/items/pink-hoody/restock",
+			body:   `{"quantity":10}`,
+			mock: func() {
+				mockService.On("Restock", mock.Anything, adminID, "pink-hoody", domain.RestockRequest{Quantity: 10}).
+					Return(&domain.CatalogItem{
+						Type: "pink-hoody", Price: 300, Category: "apparel", Available: true, Stock: &stock, LowStockThreshold: &threshold,
+					}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"type":"pink-hoody","price":300,"description":"","category":"apparel","available":true,` +
+				`"stock":15,"lowStockThreshold":5}`,
+		},
+		{
+			name:   "Restock Invalid Quantity",
+			method: http.MethodPost,
+			path:   "/items/pink-hoody/restock",
+			body:   `{"quantity":0}`,
+			mock: func() {
+				mockService.On("Restock", mock.Anything, adminID, "pink-hoody", domain.RestockRequest{}).
+					Return(nil, domain.ErrInvalidAmount).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"quantity must be 1-1000000","code":"invalid_amount"}`,
+		},
+		{
+			name:   "Create Variant",
+			method: http.MethodPost,
+			path:   "/items/hoody/variants",
+			body:   `{"sku":"hoody-XL","size":"XL","price":350}`,
+			mock: func() {
+				req := domain.CreateVariantRequest{Sku: "hoody-XL", Size: "XL", Price: &variantPrice}
+				mockService.On("CreateVariant", mock.Anything, adminID, "hoody", req).
+					Return(&domain.CatalogItem{
+						Type: "hoody", Price: 300, Category: "apparel", Available: true,
+						Variants: []domain.Variant{{Sku: "hoody-XL", Size: "XL", Price: 350}},
+					}, nil).Once()
+			},
+			expectedStatus: fiber.StatusCreated,
+			expectedBody: `{"type":"hoody","price":300,"description":"","category":"apparel","available":true,` +
+				`"variants":[{"sku":"hoody-XL","size":"XL","price":350}]}`,
+		},
+		{
+			name:   "Create Variant Duplicate",
+			method: http.MethodPost,
+			path:   "/items/hoody/variants",
+			body:   `{"sku":"hoody-XL"}`,
+			mock: func() {
+				mockService.On("CreateVariant", mock.Anything, adminID, "hoody", domain.CreateVariantRequest{Sku: "hoody-XL"}).
+					Return(nil, domain.ErrVariantAlreadyExists).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"variant already exists","code":"variant_already_exists"}`,
+		},
+		{
+			name:   "Restock Unknown Variant",
+			method: http.MethodPost,
+			path:   "/items/hoody/restock",
+			body:   `{"variant":"hoody-XXS","quantity":5}`,
+			mock: func() {
+				mockService.On("Restock", mock.Anything, adminID, "hoody", domain.RestockRequest{Variant: "hoody-XXS", Quantity: 5}).
+					Return(nil, domain.ErrVariantNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"variant not found","code":"variant_not_found"}`,
+		},
+		{
+			name:   "Price History",
+			method: http.MethodGet,
+			path:   "/items/cup/prices",
+			mock: func() {
+				mockService.On("PriceHistory", mock.Anything, "cup").Return(&domain.PriceHistoryResponse{
+					Type: "cup",
+					Changes: []domain.PriceChange{
+						{Change: "update", Price: 25, Category: "drinkware", Available: true, ChangedBy: adminID, ChangedAt: retiredAt},
+						{Change: "create", Price: 20, Category: "drinkware", Available: true, ChangedAt: retiredAt.Add(-time.Hour)},
+					},
+				}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"type":"cup","changes":[` +
+				`{"change":"update","price":25,"description":"","category":"drinkware","available":true,` +
+				`"changedBy":"` + adminID + `","changedAt":"2025-03-01T12:00:00Z"},` +
+				`{"change":"create","price":20,"description":"","category":"drinkware","available":true,` +
+				`"changedAt":"2025-03-01T11:00:00Z"}]}`,
+		},
+		{
+			name:   "Internal Server Error",
+			method: http.MethodGet,
+			path:   "/items/hoody/prices",
+			mock: func() {
+				mockService.On("PriceHistory", mock.Anything, "hoody").Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type FulfilmentService interface {
+	Advance(ctx context.Context, adminIDStr string, orderIDStr string, req domain.OrderStatusRequest) (*domain.Order, error)
+	List(ctx context.Context, req domain.AdminOrdersRequest) (*domain.OrdersResponse, error)
+	Open(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error)
+}
+
+type Fulfilment struct {
+	service FulfilmentService
+}
+
+func NewFulfilment(service FulfilmentService) Fulfilment {
+	return Fulfilment{
+		service: service,
+	}
+}
+
+// Open
+// @Tags transactions
+// @Summary Незавершённые заказы
+// @Description Заказы пользователя, которые ещё не выданы и не отменены, от новых к старым
+// @Produce json
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.OrdersResponse "Страница заказов"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/orders/open [GET]
+func (f Fulfilment) Open() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.OrdersRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return fulfilmentError(ctx, domain.ErrInvalidInput)
+		}
+
+		orders, err := f.service.Open(ctx.Context(), userIDStr, req)
+		if err != nil {
+			return fulfilmentError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(orders)
+	}
+}
+
+// List
+// @Tags admin
+// @Summary Заказы к выдаче
+// @Description Заказы всех пользователей в указанном статусе, по умолчанию все незавершённые, от новых к старым
+// @Produce json
+// @Param status query string false "Статус: created, ready, picked_up или cancelled"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.OrdersResponse "Страница заказов"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный статус, курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/orders [GET]
+func (f Fulfilment) List() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.AdminOrdersRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return fulfilmentError(ctx, domain.ErrInvalidInput)
+		}
+
+		orders, err := f.service.List(ctx.Context(), req)
+		if err != nil {
+			return fulfilmentError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(orders)
+	}
+}
+
+// Advance
+// @Tags admin
+// @Summary Смена статуса заказа
+// @Description Перевод заказа по цепочке created → ready → picked_up. Заказ, который ещё не выдан, можно отменить (cancelled), уплаченные монеты возвращаются покупателю
+// @Accept json
+// @Produce json
+// @Param id path int true "Номер заказа"
+// @Param body body domain.OrderStatusRequest true "Новый статус"
+// @Success 200 {object} domain.Order "Заказ с новым статусом"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный статус"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} domain.ErrorResponse "Недостаточно прав"
+// @Failure 404 {object} domain.ErrorResponse "Заказ не найден"
+// @Failure 409 {object} domain.ErrorResponse "Переход недопустим из текущего статуса или предметов уже нет в инвентаре"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/orders/{id}/status [POST]
+func (f Fulfilment) Advance() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		adminIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.OrderStatusRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		order, err := f.service.Advance(ctx.Context(), adminIDStr, ctx.Params("id"), req)
+		if err != nil {
+			return fulfilmentError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(order)
+	}
+}
+
+func fulfilmentError(ctx fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized", Code: domain.CodeUnauthorized})
+	case errors.Is(err, domain.ErrInvalidInput):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request", Code: domain.CodeInvalidInput})
+	case errors.Is(err, domain.ErrInvalidCursor):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid cursor", Code: domain.CodeInvalidCursor})
+	case errors.Is(err, domain.ErrInvalidOrderStatus):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{
+			Errors: "status must be created, ready, picked_up or cancelled",
+			Code:   domain.CodeInvalidOrderStatus,
+		})
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "order not found", Code: domain.CodeOrderNotFound})
+	case errors.Is(err, domain.ErrInvalidOrderTransition):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{
+			Errors: "order cannot move to this status from its current one",
+			Code:   domain.CodeInvalidOrderTransition,
+		})
+	case errors.Is(err, domain.ErrNotEnoughItems):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{Errors: "not enough items in inventory", Code: domain.CodeNotEnoughItems})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockFulfilmentService struct {
+	mock.Mock
+}
+
+func (m *MockFulfilmentService) Advance(ctx context.Context, adminIDStr string, orderIDStr string, req domain.OrderStatusRequest) (*domain.Order, error) {
+	args := m.Called(ctx, adminIDStr, orderIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockFulfilmentService) List(ctx context.Context, req domain.AdminOrdersRequest) (*domain.OrdersResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.OrdersResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockFulfilmentService) Open(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.OrdersResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestFulfilmentHandler(t *testing.T) {
+	mockService := new(MockFulfilmentService)
+
+	handler := NewFulfilment(mockService)
+
+	const userID = "0b9ce7a4-7c43-4c1e-9d8a-5c2f1f0e6a11"
+
+	app := fiber.New()
+	app.Use(func(ctx fiber.Ctx) error {
+		ctx.Locals("id", userID)
+		return ctx.Next()
+	})
+	app.Get("/orders/open", handler.Open())
+	app.Get("/admin/orders", handler.List())
+	app.Post("/admin/orders/:id/status", handler.Advance())
+
+	createdAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	order := func(status string) *domain.Order {
+		return &domain.Order{
+			ID:        7,
+			User:      "alice",
+			Status:    status,
+			Lines:     []domain.OrderLine{{ItemType: "hoody", Quantity: 1, UnitPrice: 300, Total: 300}},
+			Total:     300,
+			CreatedAt: createdAt,
+		}
+	}
+	orderBody := func(status string) string {
+		return `{"id":7,"user":"alice","status":"` + status + `",` +
+			`"lines":[{"itemType":"hoody","quantity":1,"unitPrice":300,"total":300}],"total":300,"createdAt":"2025-02-01T12:00:00Z"}`
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Open Orders",
+			method: http.MethodGet,
+			path:   "/orders/open?limit=1",
+			mock: func() {
+				mockService.On("Open", mock.Anything, userID, domain.OrdersRequest{Limit: 1}).
+					Return(&domain.OrdersResponse{Orders: []domain.Order{*order("ready")}, NextCursor: "next"}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"orders":[` + orderBody("ready") + `],"nextCursor":"next"}`,
+		},
+		{
+			name:   "List By Status",
+			method: http.MethodGet,
+			path:   "/admin/orders?status=created",
+			mock: func() {
+				mockService.On("List", mock.Anything, domain.AdminOrdersRequest{Status: "created"}).
+					Return(&domain.OrdersResponse{Orders: []domain.Order{*order("created")}}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"orders":[` + orderBody("created") + `]}`,
+		},
+		{
+			name:   "List Invalid Status",
+			method: http.MethodGet,
+			path:   "/admin/orders?status=lost",
+			mock: func() {
+				mockService.On("List", mock.Anything, domain.AdminOrdersRequest{Status: "lost"}).
+					Return(nil, domain.ErrInvalidOrderStatus).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"status must be created, ready, picked_up or cancelled","code":"invalid_order_status"}`,
+		},
+		{
+			name:   "Mark Ready",
+			method: http.MethodPost,
+			path:   "/admin/orders/7/status",
+			body:   `{"status":"ready"}`,
+			mock: func() {
+				mockService.On("Advance", mock.Anything, userID, "7", domain.OrderStatusRequest{Status: "ready"}).
+					Return(order("ready"), nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   orderBody("ready"),
+		},
+		{
+			name:   "Invalid Transition",
+			method: http.MethodPost,
+			path:   "/admin/orders/7/status",
+			body:   `{"status":"created"}`,
+			mock: func() {
+				mockService.On("Advance", mock.Anything, userID, "7", domain.OrderStatusRequest{Status: "created"}).
+					Return(nil, domain.ErrInvalidOrderTransition).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"order cannot move to this status from its current one","code":"invalid_order_transition"}`,
+		},
+		{
+			name:   "Order Not Found",
+			method: http.MethodPost,
+			path:   "/admin/orders/404/status",
+			body:   `{"status":"cancelled"}`,
+			mock: func() {
+				mockService.On("Advance", mock.Anything, userID, "404", domain.OrderStatusRequest{Status: "cancelled"}).
+					Return(nil, domain.ErrOrderNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"order not found","code":"order_not_found"}`,
+		},
+		{
+			name:   "Internal Server Error",
+			method: http.MethodPost,
+			path:   "/admin/orders/7/status",
+			body:   `{"status":"cancelled"}`,
+			mock: func() {
+				mockService.On("Advance", mock.Anything, userID, "7", domain.OrderStatusRequest{Status: "cancelled"}).
+					Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"avito_test/internal/jwt"
+	"github.com/gofiber/fiber/v3"
+)
+
+const jwksCacheControl = "public, max-age=300"
+
+type KeyService interface {
+	JWKS() jwt.JWKS
+}
+
+type Keys struct {
+	service KeyService
+}
+
+func NewKeys(service KeyService) Keys {
+	return Keys{
+		service: service,
+	}
+}
+
+// JWKS
+// @Tags auth
+// @Summary Публичные ключи
+// @Description Набор публичных ключей для проверки JWT-токенов
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Router /.well-known/jwks.json [GET]
+func (k Keys) JWKS() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		ctx.Set(fiber.HeaderCacheControl, jwksCacheControl)
+		return ctx.Status(fiber.StatusOK).JSON(k.service.JWKS())
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type KudosService interface {
+	Feed(ctx context.Context, req domain.KudosRequest) (*domain.KudosResponse, error)
+}
+
+type Kudos struct {
+	service KudosService
+}
+
+func NewKudos(service KudosService) Kudos {
+	return Kudos{
+		service: service,
+	}
+}
+
+// Feed
+// @Tags kudos
+// @Summary Лента благодарностей
+// @Description Благодарности, приложенные к переводам монет всех сотрудников, от новых к старым. Приватные переводы и переводы без сообщения и категории в ленту не попадают, сумма перевода не показывается
+// @Produce json
+// @Param category query string false "Категория: helped_me, great_review, onboarding или teamwork"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.KudosResponse "Страница благодарностей"
+// @Failure 400 {object} domain.ErrorResponse "Некорректная категория, курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /kudos [GET]
+func (k Kudos) Feed() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.KudosRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request", Code: domain.CodeInvalidInput})
+		}
+
+		feed, err := k.service.Feed(ctx.Context(), req)
+		switch {
+		case errors.Is(err, domain.ErrInvalidKudos):
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{
+				Errors: "category must be helped_me, great_review, onboarding or teamwork",
+				Code:   domain.CodeInvalidKudos,
+			})
+		case errors.Is(err, domain.ErrInvalidInput):
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request", Code: domain.CodeInvalidInput})
+		case errors.Is(err, domain.ErrInvalidCursor):
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid cursor", Code: domain.CodeInvalidCursor})
+		case err != nil:
+			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(feed)
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockKudosService struct {
+	mock.Mock
+}
+
+func (m *MockKudosService) Feed(ctx context.Context, req domain.KudosRequest) (*domain.KudosResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.KudosResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestKudosHandler_Feed(t *testing.T) {
+	mockService := new(MockKudosService)
+
+	handler := NewKudos(mockService)
+
+	app := fiber.New()
+	app.Get("/kudos", handler.Feed())
+
+	createdAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		path           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			path: "/kudos?limit=1",
+			mock: func() {
+				mockService.On("Feed", mock.Anything, domain.KudosRequest{Limit: 1}).
+					Return(&domain.KudosResponse{
+						Kudos: []domain.Kudos{{
+							ID: 3, FromUser: "alice", ToUser: "bob", Category: "helped_me", Message: "thanks!", CreatedAt: createdAt,
+						}},
+						NextCursor: "next",
+					}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"kudos":[{"id":3,"fromUser":"alice","toUser":"bob","category":"helped_me","message":"thanks!",` +
+				`"createdAt":"2025-02-01T12:00:00Z"}],"nextCursor":"next"}`,
+		},
+		{
+			name: "Empty Category Page",
+			path: "/kudos?category=onboarding",
+			mock: func() {
+				mockService.On("Feed", mock.Anything, domain.KudosRequest{Category: "onboarding"}).
+					Return(&domain.KudosResponse{Kudos: []domain.Kudos{}}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"kudos":[]}`,
+		},
+		{
+			name: "Unknown Category",
+			path: "/kudos?category=bribe",
+			mock: func() {
+				mockService.On("Feed", mock.Anything, domain.KudosRequest{Category: "bribe"}).
+					Return(nil, domain.ErrInvalidKudos).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"category must be helped_me, great_review, onboarding or teamwork","code":"invalid_kudos"}`,
+		},
+		{
+			name: "Invalid Cursor",
+			path: "/kudos?cursor=broken",
+			mock: func() {
+				mockService.On("Feed", mock.Anything, domain.KudosRequest{Cursor: "broken"}).
+					Return(nil, domain.ErrInvalidCursor).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid cursor","code":"invalid_cursor"}`,
+		},
+		{
+			name: "Internal Server Error",
+			path: "/kudos",
+			mock: func() {
+				mockService.On("Feed", mock.Anything, domain.KudosRequest{}).
+					Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type PaymentRequestService interface {
+	Create(ctx context.Context, userIDStr string, req domain.CreatePaymentRequest) (*domain.PaymentRequest, error)
+	Incoming(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error)
+	Sent(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error)
+	Approve(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error)
+	Decline(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error)
+}
+
+type PaymentRequests struct {
+	service PaymentRequestService
+}
+
+func NewPaymentRequests(service PaymentRequestService) PaymentRequests {
+	return PaymentRequests{
+		service: service,
+	}
+}
+
+// Create
+// @Tags transactions
+// @Summary Запрос монет
+// @Description Просьба другому пользователю перевести монеты. Запрос действует до истечения срока PAYMENT_REQUEST_TTL
+// @Accept json
+// @Produce json
+// @Param body body domain.CreatePaymentRequest true "Запрос"
+// @Success 201 {object} domain.PaymentRequest "Созданный запрос"
+// @Failure 400 {object} domain.ErrorResponse "Некорректная сумма или сообщение"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 404 {object} domain.ErrorResponse "Пользователь не найден"
+// @Failure 422 {object} domain.ErrorResponse "Запрос самому себе"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/requests [POST]
+func (p PaymentRequests) Create() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.CreatePaymentRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		request, err := p.service.Create(ctx.Context(), userIDStr, req)
+		if err != nil {
+			return paymentRequestError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusCreated).JSON(request)
+	}
+}
+
+// Incoming
+// @Tags transactions
+// @Summary Входящие запросы монет
+// @Description Ожидающие ответа запросы, в которых пользователя просят перевести монеты, от новых к старым
+// @Produce json
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.PaymentRequestsResponse "Страница запросов"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/requests/incoming [GET]
+func (p PaymentRequests) Incoming() fiber.Handler {
+	return p.list(p.service.Incoming)
+}
+
+// Sent
+// @Tags transactions
+// @Summary История запросов монет
+// @Description Запросы, созданные пользователем, во всех статусах, включая отклонённые и просроченные, от новых к старым
+// @Produce json
+// @Param status query string false "Статус: pending, approved, declined или expired"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.PaymentRequestsResponse "Страница запросов"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный статус, курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/requests/sent [GET]
+func (p PaymentRequests) Sent() fiber.Handler {
+	return p.list(p.service.Sent)
+}
+
+// Approve
+// @Tags transactions
+// @Summary Оплата запроса монет
+// @Description Перевод запрошенных монет автору запроса. Перевод выполняется так же, как sendCoin, и не попадает в ленту благодарностей
+// @Produce json
+// @Param id path int true "Номер запроса"
+// @Success 200 {object} domain.PaymentRequest "Оплаченный запрос"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 402 {object} domain.ErrorResponse "Недостаточно монет"
+// @Failure 404 {object} domain.ErrorResponse "Запрос не найден"
+// @Failure 409 {object} domain.ErrorResponse "Запрос уже обработан или просрочен"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/requests/{id}/approve [POST]
+func (p PaymentRequests) Approve() fiber.Handler {
+	return p.resolve(p.service.Approve)
+}
+
+// Decline
+// @Tags transactions
+// @Summary Отклонение запроса монет
+// @Description Отказ от оплаты запроса. Отклонённый запрос остаётся в истории его автора
+// @Produce json
+// @Param id path int true "Номер запроса"
+// @Success 200 {object} domain.PaymentRequest "Отклонённый запрос"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 404 {object} domain.ErrorResponse "Запрос не найден"
+// @Failure 409 {object} domain.ErrorResponse "Запрос уже обработан или просрочен"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/requests/{id}/decline [POST]
+func (p PaymentRequests) Decline() fiber.Handler {
+	return p.resolve(p.service.Decline)
+}
+
+func (p PaymentRequests) list(
+	list func(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error),
+) fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		var req domain.PaymentRequestsRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return paymentRequestError(ctx, domain.ErrInvalidInput)
+		}
+
+		requests, err := list(ctx.Context(), userIDStr, req)
+		if err != nil {
+			return paymentRequestError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(requests)
+	}
+}
+
+func (p PaymentRequests) resolve(
+	resolve func(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error),
+) fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format", Code: domain.CodeUnauthorized})
+		}
+
+		request, err := resolve(ctx.Context(), userIDStr, ctx.Params("id"))
+		if err != nil {
+			return paymentRequestError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(request)
+	}
+}
+
+func paymentRequestError(ctx fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized", Code: domain.CodeUnauthorized})
+	case errors.Is(err, domain.ErrInvalidInput):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request", Code: domain.CodeInvalidInput})
+	case errors.Is(err, domain.ErrInvalidCursor):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid cursor", Code: domain.CodeInvalidCursor})
+	case errors.Is(err, domain.ErrInvalidPaymentRequest):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{
+			Errors: "payment request needs a positive amount and a message of up to 200 characters",
+			Code:   domain.CodeInvalidPaymentRequest,
+		})
+	case errors.Is(err, domain.ErrInvalidPaymentStatus):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{
+			Errors: "status must be pending, approved, declined or expired",
+			Code:   domain.CodeInvalidPaymentStatus,
+		})
+	case errors.Is(err, domain.ErrInsufficientFunds):
+		return ctx.Status(fiber.StatusPaymentRequired).JSON(domain.ErrorResponse{Errors: "insufficient funds", Code: domain.CodeInsufficientFunds})
+	case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrRecipientNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "user not found", Code: domain.CodeUserNotFound})
+	case errors.Is(err, domain.ErrPaymentRequestNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "payment request not found", Code: domain.CodePaymentRequestNotFound})
+	case errors.Is(err, domain.ErrPaymentRequestResolved):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{
+			Errors: "payment request has already been approved or declined",
+			Code:   domain.CodePaymentRequestResolved,
+		})
+	case errors.Is(err, domain.ErrPaymentRequestExpired):
+		return ctx.Status(fiber.StatusConflict).JSON(domain.ErrorResponse{Errors: "payment request has expired", Code: domain.CodePaymentRequestExpired})
+	case errors.Is(err, domain.ErrSelfPaymentRequest):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(domain.ErrorResponse{
+			Errors: "cannot request coins from yourself",
+			Code:   domain.CodeSelfPaymentRequest,
+		})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal})
+	}
+}
@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockPaymentRequestService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestService) Create(ctx context.Context, userIDStr string, req domain.CreatePaymentRequest) (*domain.PaymentRequest, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PaymentRequest), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Incoming(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PaymentRequestsResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Sent(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PaymentRequestsResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Approve(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error) {
+	args := m.Called(ctx, userIDStr, requestIDStr)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PaymentRequest), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Decline(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error) {
+	args := m.Called(ctx, userIDStr, requestIDStr)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.PaymentRequest), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestPaymentRequestHandler(t *testing.T) {
+	mockService := new(MockPaymentRequestService)
+
+	handler := NewPaymentRequests(mockService)
+
+	const userID = "0b9ce7a4-7c43-4c1e-9d8a-5c2f1f0e6a11"
+
+	app := fiber.New()
+	app.Use(func(ctx fiber.Ctx) error {
+		ctx.Locals("id", userID)
+		return ctx.Next()
+	})
+	app.Post("/requests", handler.Create())
+	app.Get("/requests/incoming", handler.Incoming())
+	app.Get("/requests/sent", handler.Sent())
+	app.Post("/requests/:id/approve", handler.Approve())
+	app.Post("/requests/:id/decline", handler.Decline())
+
+	createdAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	request := func(status string) *domain.PaymentRequest {
+		return &domain.PaymentRequest{
+			ID:        5,
+			FromUser:  "bob",
+			ToUser:    "alice",
+			Amount:    30,
+			Message:   "lunch",
+			Status:    status,
+			ExpiresAt: createdAt.Add(7 * 24 * time.Hour),
+			CreatedAt: createdAt,
+		}
+	}
+	requestBody := func(status string) string {
+		return `{"id":5,"fromUser":"bob","toUser":"alice","amount":30,"message":"lunch","status":"` + status + `",` +
+			`"expiresAt":"2025-02-08T12:00:00Z","createdAt":"2025-02-01T12:00:00Z"}`
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Create",
+			method: http.MethodPost,
+			path:   "/requests",
+			body:   `{"fromUser":"bob","amount":30,"message":"lunch"}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, userID, domain.CreatePaymentRequest{FromUser: "bob", Amount: 30, Message: "lunch"}).
+					Return(request("pending"), nil).Once()
+			},
+			expectedStatus: fiber.StatusCreated,
+			expectedBody:   requestBody("pending"),
+		},
+		{
+			name:   "Create Invalid Amount",
+			method: http.MethodPost,
+			path:   "/requests",
+			body:   `{"fromUser":"bob","amount":0}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, userID, domain.CreatePaymentRequest{FromUser: "bob"}).
+					Return(nil, domain.ErrInvalidPaymentRequest).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody: `{"errors":"payment request needs a positive amount and a message of up to 200 characters",` +
+				`"code":"invalid_payment_request"}`,
+		},
+		{
+			name:   "Create Empty Payer",
+			method: http.MethodPost,
+			path:   "/requests",
+			body:   `{"amount":30}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, userID, domain.CreatePaymentRequest{Amount: 30}).
+					Return(nil, domain.ErrInvalidInput).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid request","code":"invalid_input"}`,
+		},
+		{
+			name:   "Create Unknown Payer",
+			method: http.MethodPost,
+			path:   "/requests",
+			body:   `{"fromUser":"ghost","amount":30}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, userID, domain.CreatePaymentRequest{FromUser: "ghost", Amount: 30}).
+					Return(nil, domain.ErrUserNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"user not found","code":"user_not_found"}`,
+		},
+		{
+			name:   "Create From Yourself",
+			method: http.MethodPost,
+			path:   "/requests",
+			body:   `{"fromUser":"alice","amount":30}`,
+			mock: func() {
+				mockService.On("Create", mock.Anything, userID, domain.CreatePaymentRequest{FromUser: "alice", Amount: 30}).
+					Return(nil, domain.ErrSelfPaymentRequest).Once()
+			},
+			expectedStatus: fiber.StatusUnprocessableEntity,
+			expectedBody:   `{"errors":"cannot request coins from yourself","code":"self_payment_request"}`,
+		},
+		{
+			name:   "Incoming",
+			method: http.MethodGet,
+			path:   "/requests/incoming?limit=1",
+			mock: func() {
+				mockService.On("Incoming", mock.Anything, userID, domain.PaymentRequestsRequest{Limit: 1}).
+					Return(&domain.PaymentRequestsResponse{Requests: []domain.PaymentRequest{*request("pending")}, NextCursor: "next"}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"requests":[` + requestBody("pending") + `],"nextCursor":"next"}`,
+		},
+		{
+			name:   "Sent Expired",
+			method: http.MethodGet,
+			path:   "/requests/sent?status=expired",
+			mock: func() {
+				mockService.On("Sent", mock.Anything, userID, domain.PaymentRequestsRequest{Status: "expired"}).
+					Return(&domain.PaymentRequestsResponse{Requests: []domain.PaymentRequest{*request("expired")}}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"requests":[` + requestBody("expired") + `]}`,
+		},
+		{
+			name:   "Sent Invalid Status",
+			method: http.MethodGet,
+			path:   "/requests/sent?status=lost",
+			mock: func() {
+				mockService.On("Sent", mock.Anything, userID, domain.PaymentRequestsRequest{Status: "lost"}).
+					Return(nil, domain.ErrInvalidPaymentStatus).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"status must be pending, approved, declined or expired","code":"invalid_payment_request_status"}`,
+		},
+		{
+			name:   "Approve",
+			method: http.MethodPost,
+			path:   "/requests/5/approve",
+			mock: func() {
+				mockService.On("Approve", mock.Anything, userID, "5").Return(request("approved"), nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   requestBody("approved"),
+		},
+		{
+			name:   "Approve Insufficient Funds",
+			method: http.MethodPost,
+			path:   "/requests/5/approve",
+			mock: func() {
+				mockService.On("Approve", mock.Anything, userID, "5").Return(nil, domain.ErrInsufficientFunds).Once()
+			},
+			expectedStatus: fiber.StatusPaymentRequired,
+			expectedBody:   `{"errors":"insufficient funds","code":"insufficient_funds"}`,
+		},
+		{
+			name:   "Approve Expired",
+			method: http.MethodPost,
+			path:   "/requests/5/approve",
+			mock: func() {
+				mockService.On("Approve", mock.Anything, userID, "5").Return(nil, domain.ErrPaymentRequestExpired).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"payment request has expired","code":"payment_request_expired"}`,
+		},
+		{
+			name:   "Decline",
+			method: http.MethodPost,
+			path:   "/requests/5/decline",
+			mock: func() {
+				mockService.On("Decline", mock.Anything, userID, "5").Return(request("declined"), nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   requestBody("declined"),
+		},
+		{
+			name:   "Decline Resolved",
+			method: http.MethodPost,
+			path:   "/requests/5/decline",
+			mock: func() {
+				mockService.On("Decline", mock.Anything, userID, "5").Return(nil, domain.ErrPaymentRequestResolved).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"payment request has already been approved or declined","code":"payment_request_resolved"}`,
+		},
+		{
+			name:   "Decline Not Found",
+			method: http.MethodPost,
+			path:   "/requests/404/decline",
+			mock: func() {
+				mockService.On("Decline", mock.Anything, userID, "404").Return(nil, domain.ErrPaymentRequestNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"payment request not found","code":"payment_request_not_found"}`,
+		},
+		{
+			name:   "Internal Server Error",
+			method: http.MethodPost,
+			path:   "/requests/5/approve",
+			mock: func() {
+				mockService.On("Approve", mock.Anything, userID, "5").Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+)
+
+type RoleService interface {
+	Grant(ctx context.Context, username string, req domain.RoleRequest) (*domain.RolesResponse, error)
+	Revoke(ctx context.Context, username string, role string) (*domain.RolesResponse, error)
+}
+
+type Role struct {
+	service RoleService
+}
+
+func NewRole(service RoleService) Role {
+	return Role{
+		service: service,
+	}
+}
+
+// Grant
+// @Tags admin
+// @Summary Выдача роли
+// @Description Выдача роли пользователю администратором
+// @Accept json
+// @Produce json
+// @Param username path string true "Имя пользователя"
+// @Param body body domain.RoleRequest true "Роль"
+// @Success 200 {object} domain.RolesResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /admin/users/{username}/roles [POST]
+func (r Role) Grant() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		var req domain.RoleRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+		}
+
+		res, err := r.service.Grant(ctx.Context(), ctx.Params("username"), req)
+		return roleResponse(ctx, res, err)
+	}
+}
+
+// Revoke
+// @Tags admin
+// @Summary Отзыв роли
+// @Description Отзыв роли у пользователя администратором
+// @Produce json
+// @Param username path string true "Имя пользователя"
+// @Param role path string true "Роль"
+// @Success 200 {object} domain.RolesResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /admin/users/{username}/roles/{role} [DELETE]
+func (r Role) Revoke() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		res, err := r.service.Revoke(ctx.Context(), ctx.Params("username"), ctx.Params("role"))
+		return roleResponse(ctx, res, err)
+	}
+}
+
+func roleResponse(ctx fiber.Ctx, res *domain.RolesResponse, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRole):
+		return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid role"})
+	case errors.Is(err, domain.ErrUserNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(domain.ErrorResponse{Errors: "user not found"})
+	case err != nil:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
+	default:
+		return ctx.Status(fiber.StatusOK).JSON(res)
+	}
+}
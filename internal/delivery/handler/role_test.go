@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) Grant(ctx context.Context, username string, req domain.RoleRequest) (*domain.RolesResponse, error) {
+	args := m.Called(ctx, username, req)
+	return args.Get(0).(*domain.RolesResponse), args.Error(1)
+}
+
+func (m *MockRoleService) Revoke(ctx context.Context, username string, role string) (*domain.RolesResponse, error) {
+	args := m.Called(ctx, username, role)
+	return args.Get(0).(*domain.RolesResponse), args.Error(1)
+}
+
+func TestRoleHandler(t *testing.T) {
+	mockService := new(MockRoleService)
+
+	handler := NewRole(mockService)
+
+	app := fiber.New()
+	app.Post("/users/:username/roles", handler.Grant())
+	app.Delete("/users/:username/roles/:role", handler.Revoke())
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "Grant",
+			method: "POST",
+			path:   "/users/alice/roles",
+			body:   `{"role": "admin"}`,
+			mock: func() {
+				mockService.On("Grant", mock.Anything, "alice", domain.RoleRequest{Role: "admin"}).
+					Return(&domain.RolesResponse{Username: "alice", Roles: []string{"admin", "user"}}, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   `{"username":"alice","roles":["admin","user"]}`,
+		},
+		{
+			name:   "Grant Invalid Role",
+			method: "POST",
+			path:   "/users/alice/roles",
+			body:   `{"role": "root"}`,
+			mock: func() {
+				mockService.On("Grant", mock.Anything, "alice", domain.RoleRequest{Role: "root"}).
+					Return((*domain.RolesResponse)(nil), domain.ErrInvalidRole)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid role"}`,
+		},
+		{
+			name:   "Revoke Unknown User",
+			method: "DELETE",
+			path:   "/users/bob/roles/admin",
+			mock: func() {
+				mockService.On("Revoke", mock.Anything, "bob", "admin").
+					Return((*domain.RolesResponse)(nil), domain.ErrUserNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"user not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -8,9 +8,14 @@ import (
 )
 
 type TransactionService interface {
-	Buy(ctx context.Context, userIDStr string, itemType string) error
-	Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest) error
+	Buy(ctx context.Context, userIDStr string, itemType string, variant string, idempotencyKey string) (*domain.StoredResponse, error)
+	Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest, idempotencyKey string) (*domain.StoredResponse, error)
 	Info(ctx context.Context, userIDStr string) (*domain.InfoResponse, error)
+	History(ctx context.Context, userIDStr string, req domain.HistoryRequest) (*domain.HistoryResponse, error)
+	Orders(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error)
+	Gift(ctx context.Context, userIDStr string, req domain.GiftRequest, idempotencyKey string) (*domain.StoredResponse, error)
+	Checkout(ctx context.Context, userIDStr string, req domain.CheckoutRequest, idempotencyKey string) (*domain.Order, bool, error)
+	Return(ctx context.Context, userIDStr string, req domain.ReturnRequest, idempotencyKey string) (*domain.Refund, bool, error)
 }
 
 type Transaction struct {
@@ -30,9 +35,16 @@ func NewTransaction(service TransactionService) Transaction {
 // @Accept json
 // @Produce json
 // @Param item path string true "Тип предмета"
+// @Param variant query string false "SKU варианта, обязателен для предметов с вариантами"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
 // @Success 200 "Успешная покупка"
-// @Failure 400 {object} domain.ErrorResponse "Некорректные учетные данные"
+// @Failure 400 {object} domain.ErrorResponse "Некорректные учетные данные или не выбран вариант"
 // @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 402 {object} domain.ErrorResponse "Недостаточно монет"
+// @Failure 404 {object} domain.ErrorResponse "Предмет или вариант не найден"
+// @Failure 409 {object} domain.ErrorResponse "Предмет недоступен для покупки или закончился"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 422 {object} domain.ErrorResponse "Ключ идемпотентности использован с другим запросом"
 // @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
 // @Router /transactions/buy/{item} [GET]
 func (t Transaction) Buy() fiber.Handler {
@@ -44,14 +56,12 @@ func (t Transaction) Buy() fiber.Handler {
 
 		itemType := ctx.Params("item")
 
-		err := t.service.Buy(ctx.Context(), userIDStr, itemType)
+		replay, err := t.service.Buy(ctx.Context(), userIDStr, itemType, ctx.Query("variant"), ctx.Get(domain.IdempotencyKeyHeader))
 		switch {
-		case errors.Is(err, domain.ErrInvalidCredentials):
-			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid credentials"})
-		case errors.Is(err, domain.ErrUnauthorized):
-			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
 		case err != nil:
-			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
+			return transactionError(ctx, err)
+		case replay != nil:
+			return replayResponse(ctx, replay)
 		default:
 			return ctx.SendStatus(fiber.StatusOK)
 		}
@@ -61,13 +71,17 @@ func (t Transaction) Buy() fiber.Handler {
 // Send
 // @Tags transactions
 // @Summary Отправка монет
-// @Description Отправка монет другому пользователю
+// @Description Отправка монет другому пользователю. К переводу можно приложить благодарность: сообщение до 200 символов и категорию (helped_me, great_review, onboarding, teamwork). Если перевод не помечен как private, благодарность попадает в общую ленту
 // @Accept json
 // @Produce json
 // @Param body body domain.SendCoinRequest true "Данные для перевода"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
 // @Success 200 "Перевод успешно выполнен"
-// @Failure 400 {object} domain.ErrorResponse "Некорректные учетные данные или тело запроса"
+// @Failure 400 {object} domain.ErrorResponse "Некорректные учетные данные, тело запроса или благодарность"
 // @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 402 {object} domain.ErrorResponse "Недостаточно монет"
+// @Failure 404 {object} domain.ErrorResponse "Получатель не найден"
+// @Failure 422 {object} domain.ErrorResponse "Перевод самому себе или повторный ключ идемпотентности"
 // @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
 // @Router /transactions/send [POST]
 func (t Transaction) Send() fiber.Handler {
@@ -79,23 +93,145 @@ func (t Transaction) Send() fiber.Handler {
 
 		var req domain.SendCoinRequest
 		if err := ctx.Bind().Body(&req); err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body"})
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
 		}
 
-		err := t.service.Send(ctx.Context(), userIDStr, req)
+		replay, err := t.service.Send(ctx.Context(), userIDStr, req, ctx.Get(domain.IdempotencyKeyHeader))
 		switch {
-		case errors.Is(err, domain.ErrInvalidCredentials):
-			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid credentials"})
-		case errors.Is(err, domain.ErrUnauthorized):
-			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
 		case err != nil:
-			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
+			return transactionError(ctx, err)
+		case replay != nil:
+			return replayResponse(ctx, replay)
 		default:
 			return ctx.SendStatus(fiber.StatusOK)
 		}
 	}
 }
 
+// Gift
+// @Tags transactions
+// @Summary Подарок предмета
+// @Description Покупка предмета в подарок другому пользователю за счёт отправителя или передача предмета из своего инвентаря (source=inventory)
+// @Accept json
+// @Produce json
+// @Param body body domain.GiftRequest true "Подарок"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
+// @Success 200 "Подарок отправлен"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный подарок"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 402 {object} domain.ErrorResponse "Недостаточно монет"
+// @Failure 404 {object} domain.ErrorResponse "Получатель, предмет или вариант не найден"
+// @Failure 409 {object} domain.ErrorResponse "Предмет недоступен, закончился или его нет в инвентаре"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 422 {object} domain.ErrorResponse "Подарок самому себе или повторный ключ идемпотентности"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/gift [POST]
+func (t Transaction) Gift() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		var req domain.GiftRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		replay, err := t.service.Gift(ctx.Context(), userIDStr, req, ctx.Get(domain.IdempotencyKeyHeader))
+		switch {
+		case err != nil:
+			return transactionError(ctx, err)
+		case replay != nil:
+			return replayResponse(ctx, replay)
+		default:
+			return ctx.SendStatus(fiber.StatusOK)
+		}
+	}
+}
+
+// Checkout
+// @Tags transactions
+// @Summary Оформление заказа
+// @Description Покупка нескольких предметов одним заказом: либо покупаются все позиции, либо ни одна
+// @Accept json
+// @Produce json
+// @Param body body domain.CheckoutRequest true "Позиции корзины"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
+// @Success 201 {object} domain.Order "Чек заказа"
+// @Failure 400 {object} domain.ErrorResponse "Некорректная корзина или не выбран вариант"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 402 {object} domain.ErrorResponse "Недостаточно монет"
+// @Failure 404 {object} domain.ErrorResponse "Предмет или вариант не найден"
+// @Failure 409 {object} domain.ErrorResponse "Предмет недоступен для покупки или закончился"
+// @Failure 410 {object} domain.ErrorResponse "Предмет снят с продажи"
+// @Failure 422 {object} domain.ErrorResponse "Ключ идемпотентности использован с другим запросом"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/checkout [POST]
+func (t Transaction) Checkout() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		var req domain.CheckoutRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		receipt, replayed, err := t.service.Checkout(ctx.Context(), userIDStr, req, ctx.Get(domain.IdempotencyKeyHeader))
+		if err != nil {
+			return transactionError(ctx, err)
+		}
+		if replayed {
+			ctx.Set("Idempotent-Replayed", "true")
+		}
+
+		return ctx.Status(fiber.StatusCreated).JSON(receipt)
+	}
+}
+
+// Return
+// @Tags transactions
+// @Summary Возврат предмета
+// @Description Возврат купленного предмета с возмещением уплаченной цены. Доступен в течение окна возврата и только до выдачи заказа
+// @Accept json
+// @Produce json
+// @Param body body domain.ReturnRequest true "Позиция заказа для возврата"
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
+// @Success 200 {object} domain.Refund "Возврат оформлен"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный запрос на возврат"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 404 {object} domain.ErrorResponse "Заказ или позиция не найдены"
+// @Failure 409 {object} domain.ErrorResponse "Окно возврата истекло, заказ выдан или отменён, заказ был подарком или предметов недостаточно"
+// @Failure 422 {object} domain.ErrorResponse "Ключ идемпотентности использован с другим запросом"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/return [POST]
+func (t Transaction) Return() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		var req domain.ReturnRequest
+		if err := ctx.Bind().Body(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput})
+		}
+
+		refund, replayed, err := t.service.Return(ctx.Context(), userIDStr, req, ctx.Get(domain.IdempotencyKeyHeader))
+		if err != nil {
+			return transactionError(ctx, err)
+		}
+		if replayed {
+			ctx.Set("Idempotent-Replayed", "true")
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(refund)
+	}
+}
+
 // Info
 // @Tags transactions
 // @Summary Информация о транзакциях
@@ -115,15 +251,176 @@ func (t Transaction) Info() fiber.Handler {
 		}
 
 		info, err := t.service.Info(ctx.Context(), userIDStr)
-		switch {
-		case errors.Is(err, domain.ErrInvalidCredentials):
-			return ctx.Status(fiber.StatusBadRequest).JSON(domain.ErrorResponse{Errors: "invalid credentials"})
-		case errors.Is(err, domain.ErrUnauthorized):
-			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "unauthorized"})
-		case err != nil:
-			return ctx.Status(fiber.StatusInternalServerError).JSON(domain.ErrorResponse{Errors: "internal server error"})
-		default:
-			return ctx.Status(fiber.StatusOK).JSON(info)
+		if err != nil {
+			return transactionError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(info)
+	}
+}
+
+// History
+// @Tags transactions
+// @Summary История переводов
+// @Description Постраничная история переводов, возвратов и запросов монет пользователя, от новых к старым. Возврат приходит с direction refund и orderId исходного заказа, отклонённый или просроченный запрос монет — с direction request и status declined или expired
+// @Accept json
+// @Produce json
+// @Param direction query string false "Направление: sent, received, refund или request"
+// @Param counterparty query string false "Имя второго участника перевода"
+// @Param minAmount query int false "Минимальная сумма"
+// @Param maxAmount query int false "Максимальная сумма"
+// @Param from query string false "Начало периода (RFC 3339)"
+// @Param to query string false "Конец периода (RFC 3339), не включительно"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.HistoryResponse "Страница истории"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный фильтр или курсор"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/history [GET]
+func (t Transaction) History() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		var req domain.HistoryRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return transactionError(ctx, domain.ErrInvalidHistoryFilter)
+		}
+
+		history, err := t.service.History(ctx.Context(), userIDStr, req)
+		if err != nil {
+			return transactionError(ctx, err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(history)
+	}
+}
+
+// Orders
+// @Tags transactions
+// @Summary История покупок
+// @Description Постраничный список заказов пользователя с ценой на момент покупки
+// @Accept json
+// @Produce json
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} domain.OrdersResponse "Страница заказов"
+// @Failure 400 {object} domain.ErrorResponse "Некорректный курсор или размер страницы"
+// @Failure 401 {object} domain.ErrorResponse "Неавторизованный доступ"
+// @Failure 500 {object} domain.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /transactions/orders [GET]
+func (t Transaction) Orders() fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		userIDStr, ok := ctx.Locals("id").(string)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(domain.ErrorResponse{Errors: "invalid user ID format"})
+		}
+
+		var req domain.OrdersRequest
+		if err := ctx.Bind().Query(&req); err != nil {
+			return transactionError(ctx, domain.ErrInvalidInput)
+		}
+
+		orders, err := t.service.Orders(ctx.Context(), userIDStr, req)
+		if err != nil {
+			return transactionError(ctx, err)
 		}
+
+		return ctx.Status(fiber.StatusOK).JSON(orders)
+	}
+}
+
+// transactionError maps service errors to a status and a machine-readable code.
+func transactionError(ctx fiber.Ctx, err error) error {
+	status, res := fiber.StatusInternalServerError, domain.ErrorResponse{Errors: "internal server error", Code: domain.CodeInternal}
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "invalid credentials", Code: domain.CodeInvalidCredentials}
+	case errors.Is(err, domain.ErrUnauthorized):
+		status, res = fiber.StatusUnauthorized, domain.ErrorResponse{Errors: "unauthorized", Code: domain.CodeUnauthorized}
+	case errors.Is(err, domain.ErrInvalidInput):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "invalid request body", Code: domain.CodeInvalidInput}
+	case errors.Is(err, domain.ErrInvalidAmount):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "amount must be positive", Code: domain.CodeInvalidAmount}
+	case errors.Is(err, domain.ErrInvalidKudos):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{
+			Errors: "kudos needs a known category and a message of up to 200 characters",
+			Code:   domain.CodeInvalidKudos,
+		}
+	case errors.Is(err, domain.ErrInvalidCursor):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "invalid cursor", Code: domain.CodeInvalidCursor}
+	case errors.Is(err, domain.ErrInvalidHistoryFilter):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "invalid history filter", Code: domain.CodeInvalidHistoryFilter}
+	case errors.Is(err, domain.ErrInvalidIdempotencyKey):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "invalid idempotency key", Code: domain.CodeInvalidIdempotencyKey}
+	case errors.Is(err, domain.ErrInsufficientFunds):
+		status, res = fiber.StatusPaymentRequired, domain.ErrorResponse{Errors: "insufficient funds", Code: domain.CodeInsufficientFunds}
+	case errors.Is(err, domain.ErrRecipientNotFound):
+		status, res = fiber.StatusNotFound, domain.ErrorResponse{Errors: "recipient not found", Code: domain.CodeRecipientNotFound}
+	case errors.Is(err, domain.ErrInvalidCart):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{
+			Errors: "cart must hold 1-50 lines with quantities of 1-100",
+			Code:   domain.CodeInvalidCart,
+		}
+	case errors.Is(err, domain.ErrItemNotFound):
+		status, res = fiber.StatusNotFound, domain.ErrorResponse{Errors: "item not found", Code: domain.CodeItemNotFound}
+	case errors.Is(err, domain.ErrVariantNotFound):
+		status, res = fiber.StatusNotFound, domain.ErrorResponse{Errors: "variant not found", Code: domain.CodeVariantNotFound}
+	case errors.Is(err, domain.ErrVariantRequired):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{Errors: "item has variants, pick one", Code: domain.CodeVariantRequired}
+	case errors.Is(err, domain.ErrItemUnavailable):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "item is not available", Code: domain.CodeItemUnavailable}
+	case errors.Is(err, domain.ErrOutOfStock):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "item is out of stock", Code: domain.CodeOutOfStock}
+	case errors.Is(err, domain.ErrItemRetired):
+		status, res = fiber.StatusGone, domain.ErrorResponse{Errors: "item is retired", Code: domain.CodeItemRetired}
+	case errors.Is(err, domain.ErrInvalidGift):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{
+			Errors: "gift needs a valid item, a quantity of 1-100, a source of purchase or inventory and a message of up to 500 characters",
+			Code:   domain.CodeInvalidGift,
+		}
+	case errors.Is(err, domain.ErrInvalidReturn):
+		status, res = fiber.StatusBadRequest, domain.ErrorResponse{
+			Errors: "return needs an order id, an item type and a quantity of 1-100",
+			Code:   domain.CodeInvalidReturn,
+		}
+	case errors.Is(err, domain.ErrOrderNotFound):
+		status, res = fiber.StatusNotFound, domain.ErrorResponse{Errors: "order not found", Code: domain.CodeOrderNotFound}
+	case errors.Is(err, domain.ErrRefundNotFound):
+		status, res = fiber.StatusNotFound, domain.ErrorResponse{Errors: "refund not found", Code: domain.CodeRefundNotFound}
+	case errors.Is(err, domain.ErrReturnWindowClosed):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "return window has closed", Code: domain.CodeReturnWindowClosed}
+	case errors.Is(err, domain.ErrOrderPickedUp):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "order has already been picked up", Code: domain.CodeOrderPickedUp}
+	case errors.Is(err, domain.ErrOrderCancelled):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "order has been cancelled", Code: domain.CodeOrderCancelled}
+	case errors.Is(err, domain.ErrGiftNotReturnable):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "gifts cannot be returned", Code: domain.CodeGiftNotReturnable}
+	case errors.Is(err, domain.ErrNotEnoughItems):
+		status, res = fiber.StatusConflict, domain.ErrorResponse{Errors: "not enough items in inventory", Code: domain.CodeNotEnoughItems}
+	case errors.Is(err, domain.ErrSelfTransfer):
+		status, res = fiber.StatusUnprocessableEntity, domain.ErrorResponse{Errors: "cannot send coins to yourself", Code: domain.CodeSelfTransfer}
+	case errors.Is(err, domain.ErrSelfGift):
+		status, res = fiber.StatusUnprocessableEntity, domain.ErrorResponse{Errors: "cannot send a gift to yourself", Code: domain.CodeSelfGift}
+	case errors.Is(err, domain.ErrIdempotencyKeyMismatch):
+		status, res = fiber.StatusUnprocessableEntity, domain.ErrorResponse{
+			Errors: "idempotency key reused with a different request",
+			Code:   domain.CodeIdempotencyKeyMismatch,
+		}
+	}
+
+	return ctx.Status(status).JSON(res)
+}
+
+func replayResponse(ctx fiber.Ctx, replay *domain.StoredResponse) error {
+	ctx.Set("Idempotent-Replayed", "true")
+	if len(replay.Body) == 0 {
+		return ctx.SendStatus(replay.StatusCode)
 	}
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return ctx.Status(replay.StatusCode).Send(replay.Body)
 }
@@ -3,7 +3,11 @@ package handler
 import (
 	"avito_test/internal/config"
 	"avito_test/internal/domain"
+	"avito_test/internal/entity"
 	"avito_test/internal/jwt"
+	"avito_test/internal/middleware"
+	"avito_test/internal/repository"
+	"avito_test/pkg/logger"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -12,23 +16,45 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type MockTransactionService struct {
 	mock.Mock
 }
 
-func (m *MockTransactionService) Buy(ctx context.Context, userIDStr string, itemType string) error {
-	args := m.Called(ctx, userIDStr, itemType)
-	return args.Error(0)
+func (m *MockTransactionService) Buy(
+	ctx context.Context,
+	userIDStr string,
+	itemType string,
+	variant string,
+	idempotencyKey string,
+) (*domain.StoredResponse, error) {
+	args := m.Called(ctx, userIDStr, itemType, variant, idempotencyKey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.StoredResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockTransactionService) Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest) error {
-	args := m.Called(ctx, userIDStr, req)
-	return args.Error(0)
+func (m *MockTransactionService) Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest, idempotencyKey string) (*domain.StoredResponse, error) {
+	args := m.Called(ctx, userIDStr, req, idempotencyKey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.StoredResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionService) Gift(ctx context.Context, userIDStr string, req domain.GiftRequest, idempotencyKey string) (*domain.StoredResponse, error) {
+	args := m.Called(ctx, userIDStr, req, idempotencyKey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.StoredResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
 func (m *MockTransactionService) Info(ctx context.Context, userIDStr string) (*domain.InfoResponse, error) {
@@ -39,11 +65,59 @@ func (m *MockTransactionService) Info(ctx context.Context, userIDStr string) (*d
 	return nil, args.Error(1)
 }
 
-func TestTransactionHandler_Buy(t *testing.T) {
-	mockService := new(MockTransactionService)
+func (m *MockTransactionService) History(ctx context.Context, userIDStr string, req domain.HistoryRequest) (*domain.HistoryResponse, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.HistoryResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
 
-	handler := NewTransaction(mockService)
-	app := fiber.New()
+func (m *MockTransactionService) Orders(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error) {
+	args := m.Called(ctx, userIDStr, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.OrdersResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionService) Checkout(
+	ctx context.Context,
+	userIDStr string,
+	req domain.CheckoutRequest,
+	idempotencyKey string,
+) (*domain.Order, bool, error) {
+	args := m.Called(ctx, userIDStr, req, idempotencyKey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.Order), args.Bool(1), args.Error(2)
+	}
+	return nil, args.Bool(1), args.Error(2)
+}
+
+func (m *MockTransactionService) Return(
+	ctx context.Context,
+	userIDStr string,
+	req domain.ReturnRequest,
+	idempotencyKey string,
+) (*domain.Refund, bool, error) {
+	args := m.Called(ctx, userIDStr, req, idempotencyKey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*domain.Refund), args.Bool(1), args.Error(2)
+	}
+	return nil, args.Bool(1), args.Error(2)
+}
+
+// noRevokedTokens is a revocation store in which no token has been revoked.
+type noRevokedTokens struct{}
+
+func (noRevokedTokens) GetRevokedAccessToken(context.Context, string) (*entity.RevokedToken, error) {
+	return nil, nil
+}
+
+// newAuthorizedApp routes requests through the real JWT middleware and returns
+// a token for a freshly generated user ID.
+func newAuthorizedApp(t *testing.T) (*fiber.App, fiber.Router, string, string) {
+	t.Helper()
 
 	cfg := &config.Config{
 		Auth: struct {
@@ -53,63 +127,121 @@ func TestTransactionHandler_Buy(t *testing.T) {
 		},
 	}
 
+	apiLogger := logger.NewApiLogger(cfg)
+	require.NoError(t, apiLogger.InitLogger())
+
 	jwtService := jwt.NewJWTService(cfg)
+	mw := middleware.NewMDWManager(jwtService, repository.NewMemoryAuthCache(), noRevokedTokens{}, apiLogger)
 
 	validUserID := uuid.New().String()
-	invalidUserID := "invalid-uuid"
-
 	token, err := jwtService.GenerateJWT(jwt.Claims{
 		ID:       validUserID,
 		Username: "testuser",
 	})
-
 	require.NoError(t, err)
 
-	app.Post("/buy/:itemType", handler.Buy())
+	app := fiber.New()
+	group := app.Group("/", mw.JWTMiddleware())
+
+	return app, group, validUserID, token
+}
+
+func TestTransactionHandler_Buy(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Get("/buy/:item", handler.Buy())
 
 	tests := []struct {
 		name           string
-		userID         string
 		itemType       string
+		variant        string
 		mock           func()
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
 			name:     "Success",
-			userID:   validUserID,
 			itemType: "t-shirt",
 			mock: func() {
-				mockService.On("Buy", mock.Anything, validUserID, "t-shirt").Return(nil)
+				mockService.On("Buy", mock.Anything, validUserID, "t-shirt", "", "").Return(nil, nil)
 			},
 			expectedStatus: fiber.StatusOK,
-			expectedBody:   `{}`,
 		},
 		{
-			name:           "Invalid UUID",
-			userID:         invalidUserID,
-			itemType:       "cup",
-			mock:           func() {},
+			name:     "Invalid Item Type",
+			itemType: "!!!",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "!!!", "", "").Return(nil, domain.ErrInvalidCredentials)
+			},
 			expectedStatus: fiber.StatusBadRequest,
-			expectedBody:   `{"errors":"invalid user ID format"}`,
+			expectedBody:   `{"errors":"invalid credentials","code":"invalid_credentials"}`,
 		},
 		{
-			name:           "Invalid Item Type",
-			userID:         validUserID,
-			itemType:       "!!!",
-			mock:           func() {},
+			name:     "Item Not Found",
+			itemType: "yacht",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "yacht", "", "").Return(nil, domain.ErrItemNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"item not found","code":"item_not_found"}`,
+		},
+		{
+			name:     "Insufficient Funds",
+			itemType: "pink-hoody",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "pink-hoody", "", "").Return(nil, domain.ErrInsufficientFunds)
+			},
+			expectedStatus: fiber.StatusPaymentRequired,
+			expectedBody:   `{"errors":"insufficient funds","code":"insufficient_funds"}`,
+		},
+		{
+			name:     "Out Of Stock",
+			itemType: "limited-hoody",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "limited-hoody", "", "").Return(nil, domain.ErrOutOfStock)
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"item is out of stock","code":"out_of_stock"}`,
+		},
+		{
+			name:     "Variant",
+			itemType: "hoody",
+			variant:  "hoody-XL",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "hoody", "hoody-XL", "").Return(nil, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:     "Variant Required",
+			itemType: "t-shirt-sized",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "t-shirt-sized", "", "").Return(nil, domain.ErrVariantRequired)
+			},
 			expectedStatus: fiber.StatusBadRequest,
-			expectedBody:   `{"errors":"invalid item type format"}`,
+			expectedBody:   `{"errors":"item has variants, pick one","code":"variant_required"}`,
+		},
+		{
+			name:     "Variant Not Found",
+			itemType: "t-shirt-sized",
+			variant:  "t-shirt-XXXL",
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "t-shirt-sized", "t-shirt-XXXL", "").Return(nil, domain.ErrVariantNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"variant not found","code":"variant_not_found"}`,
 		},
 		{
 			name:     "Internal Server Error",
-			userID:   validUserID,
 			itemType: "hoody",
 			mock: func() {
-				mockService.On("Buy", mock.Anything, validUserID, "hoody").Return(errors.New("db error"))
+				mockService.On("Buy", mock.Anything, validUserID, "hoody", "", "").Return(nil, errors.New("db error"))
 			},
 			expectedStatus: fiber.StatusInternalServerError,
-			expectedBody:   `{"errors":"internal server error"}`,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
 		},
 	}
 
@@ -117,17 +249,23 @@ func TestTransactionHandler_Buy(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 
-			req := httptest.NewRequest(http.MethodPost, "/buy/"+tt.itemType, bytes.NewBuffer(nil))
-			req.Header.Set("Content-Type", "application/json")
+			path := "/buy/" + tt.itemType
+			if tt.variant != "" {
+				path += "?variant=" + tt.variant
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
 			req.Header.Set("Authorization", "Bearer "+token)
 
-			resp, _ := app.Test(req)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
 
 			require.Equal(t, tt.expectedStatus, resp.StatusCode)
 
-			var body map[string]string
-			_ = json.NewDecoder(resp.Body).Decode(&body)
-			require.Equal(t, tt.expectedBody, body["errors"])
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, string(body))
+			}
 		})
 	}
 }
@@ -136,71 +274,134 @@ func TestTransactionHandler_Send(t *testing.T) {
 	mockService := new(MockTransactionService)
 
 	handler := NewTransaction(mockService)
-	app := fiber.New()
+	app, group, validUserID, token := newAuthorizedApp(t)
 
-	cfg := &config.Config{
-		Auth: struct {
-			Secret string `json:"secret"`
-		}{
-			Secret: "secret",
-		},
-	}
-
-	jwtService := jwt.NewJWTService(cfg)
-
-	validUserID := uuid.New().String()
-	token, err := jwtService.GenerateJWT(jwt.Claims{
-		ID:       validUserID,
-		Username: "testuser",
-	})
-	require.NoError(t, err)
-
-	app.Post("/send", handler.Send())
+	group.Post("/send", handler.Send())
 
 	tests := []struct {
 		name           string
-		userID         string
 		requestBody    domain.SendCoinRequest
 		mock           func()
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
-			name:   "Success",
-			userID: validUserID,
+			name: "Success",
 			requestBody: domain.SendCoinRequest{
 				ToUser: "test_user",
 				Amount: 10,
 			},
 			mock: func() {
-				mockService.On("Send", mock.Anything, validUserID, mock.Anything).Return(nil)
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 10}, "").
+					Return(nil, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Success With Kudos",
+			requestBody: domain.SendCoinRequest{
+				ToUser:   "test_user",
+				Amount:   20,
+				Message:  "thanks for the review",
+				Category: "great_review",
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{
+					ToUser: "test_user", Amount: 20, Message: "thanks for the review", Category: "great_review",
+				}, "").Return(nil, nil)
 			},
 			expectedStatus: fiber.StatusOK,
-			expectedBody:   `{}`,
 		},
 		{
-			name:   "Invalid request body",
-			userID: validUserID,
+			name: "Invalid Kudos",
+			requestBody: domain.SendCoinRequest{
+				ToUser:   "test_user",
+				Amount:   20,
+				Category: "bribe",
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 20, Category: "bribe"}, "").
+					Return(nil, domain.ErrInvalidKudos)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"kudos needs a known category and a message of up to 200 characters","code":"invalid_kudos"}`,
+		},
+		{
+			name: "Invalid Amount",
 			requestBody: domain.SendCoinRequest{
 				ToUser: "test_user",
 				Amount: -5,
 			},
-			mock:           func() {},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: -5}, "").
+					Return(nil, domain.ErrInvalidAmount)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"amount must be positive","code":"invalid_amount"}`,
+		},
+		{
+			name: "Empty Recipient",
+			requestBody: domain.SendCoinRequest{
+				Amount: 10,
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{Amount: 10}, "").
+					Return(nil, domain.ErrInvalidInput)
+			},
 			expectedStatus: fiber.StatusBadRequest,
-			expectedBody:   `{"errors":"invalid request body"}`,
+			expectedBody:   `{"errors":"invalid request body","code":"invalid_input"}`,
 		},
 		{
-			name:   "Internal Server Error",
-			userID: validUserID,
+			name: "Recipient Not Found",
+			requestBody: domain.SendCoinRequest{
+				ToUser: "ghost",
+				Amount: 10,
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "ghost", Amount: 10}, "").
+					Return(nil, domain.ErrRecipientNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"recipient not found","code":"recipient_not_found"}`,
+		},
+		{
+			name: "Self Transfer",
+			requestBody: domain.SendCoinRequest{
+				ToUser: "testuser",
+				Amount: 10,
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "testuser", Amount: 10}, "").
+					Return(nil, domain.ErrSelfTransfer)
+			},
+			expectedStatus: fiber.StatusUnprocessableEntity,
+			expectedBody:   `{"errors":"cannot send coins to yourself","code":"self_transfer"}`,
+		},
+		{
+			name: "Insufficient Funds",
+			requestBody: domain.SendCoinRequest{
+				ToUser: "test_user",
+				Amount: 100000,
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 100000}, "").
+					Return(nil, domain.ErrInsufficientFunds)
+			},
+			expectedStatus: fiber.StatusPaymentRequired,
+			expectedBody:   `{"errors":"insufficient funds","code":"insufficient_funds"}`,
+		},
+		{
+			name: "Internal Server Error",
 			requestBody: domain.SendCoinRequest{
 				ToUser: "test_user",
 				Amount: 15,
 			},
 			mock: func() {
-				mockService.On("Send", mock.Anything, validUserID, mock.Anything).Return(errors.New("db error"))
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 15}, "").
+					Return(nil, errors.New("db error"))
 			},
 			expectedStatus: fiber.StatusInternalServerError,
-			expectedBody:   `{"errors":"internal server error"}`,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
 		},
 	}
 
@@ -213,57 +414,153 @@ func TestTransactionHandler_Send(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer "+token)
 
-			resp, _ := app.Test(req)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
 
 			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, string(respBody))
+			}
 		})
 	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/send", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
 }
 
-func TestTransactionHandler_Info(t *testing.T) {
+func TestTransactionHandler_Gift(t *testing.T) {
 	mockService := new(MockTransactionService)
 
 	handler := NewTransaction(mockService)
-	app := fiber.New()
+	app, group, validUserID, token := newAuthorizedApp(t)
 
-	cfg := &config.Config{
-		Auth: struct {
-			Secret string `json:"secret"`
-		}{
-			Secret: "secret",
+	group.Post("/gift", handler.Gift())
+
+	tests := []struct {
+		name           string
+		requestBody    domain.GiftRequest
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "Success",
+			requestBody: domain.GiftRequest{ToUser: "friend", Type: "cup", Message: "Спасибо!"},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "friend", Type: "cup", Message: "Спасибо!"}, "").
+					Return(nil, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:        "Invalid Gift",
+			requestBody: domain.GiftRequest{ToUser: "friend", Type: "cup", Quantity: 1000},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "friend", Type: "cup", Quantity: 1000}, "").
+					Return(nil, domain.ErrInvalidGift)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody: `{"errors":"gift needs a valid item, a quantity of 1-100, a source of purchase or inventory and a message of up to 500 characters",` +
+				`"code":"invalid_gift"}`,
+		},
+		{
+			name:        "Empty Recipient",
+			requestBody: domain.GiftRequest{Type: "cup"},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{Type: "cup"}, "").
+					Return(nil, domain.ErrInvalidInput)
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid request body","code":"invalid_input"}`,
+		},
+		{
+			name:        "Self Gift",
+			requestBody: domain.GiftRequest{ToUser: "testuser", Type: "cup"},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "testuser", Type: "cup"}, "").
+					Return(nil, domain.ErrSelfGift)
+			},
+			expectedStatus: fiber.StatusUnprocessableEntity,
+			expectedBody:   `{"errors":"cannot send a gift to yourself","code":"self_gift"}`,
+		},
+		{
+			name:        "Not Enough Items",
+			requestBody: domain.GiftRequest{ToUser: "friend", Type: "pen", Quantity: 3, Source: domain.GiftSourceInventory},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "friend", Type: "pen", Quantity: 3, Source: domain.GiftSourceInventory}, "").
+					Return(nil, domain.ErrNotEnoughItems)
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"not enough items in inventory","code":"not_enough_items"}`,
+		},
+		{
+			name:        "Internal Server Error",
+			requestBody: domain.GiftRequest{ToUser: "friend", Type: "hoody"},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "friend", Type: "hoody"}, "").
+					Return(nil, errors.New("db error"))
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
 		},
 	}
 
-	jwtService := jwt.NewJWTService(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
 
-	validUserID := uuid.New().String()
-	token, err := jwtService.GenerateJWT(jwt.Claims{
-		ID:       validUserID,
-		Username: "testuser",
-	})
-	require.NoError(t, err)
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/gift", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
 
-	app.Get("/info", handler.Info())
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, string(respBody))
+			}
+		})
+	}
+}
+
+func TestTransactionHandler_Info(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Get("/info", handler.Info())
 
 	tests := []struct {
 		name           string
-		userID         string
 		mock           func()
 		expectedStatus int
 	}{
 		{
-			name:   "Success",
-			userID: validUserID,
+			name: "Success",
 			mock: func() {
-				mockService.On("Info", mock.Anything, validUserID).Return(&domain.InfoResponse{}, nil)
+				mockService.On("Info", mock.Anything, validUserID).Return(&domain.InfoResponse{}, nil).Once()
 			},
 			expectedStatus: fiber.StatusOK,
 		},
 		{
-			name:   "Internal Server Error",
-			userID: validUserID,
+			name: "Internal Server Error",
 			mock: func() {
-				mockService.On("Info", mock.Anything, validUserID).Return(nil, errors.New("db error"))
+				mockService.On("Info", mock.Anything, validUserID).Return(nil, errors.New("db error")).Once()
 			},
 			expectedStatus: fiber.StatusInternalServerError,
 		},
@@ -276,9 +573,516 @@ func TestTransactionHandler_Info(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/info", nil)
 			req.Header.Set("Authorization", "Bearer "+token)
 
-			resp, _ := app.Test(req)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestTransactionHandler_History(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Get("/history", handler.History())
+
+	createdAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "Success",
+			query: "?direction=sent&counterparty=bob&minAmount=10&limit=1",
+			mock: func() {
+				req := domain.HistoryRequest{Direction: "sent", Counterparty: "bob", MinAmount: 10, Limit: 1}
+				mockService.On("History", mock.Anything, validUserID, req).Return(&domain.HistoryResponse{
+					Transactions: []domain.HistoryEntry{
+						{ID: 7, Direction: "sent", FromUser: "testuser", ToUser: "bob", Amount: 15, CreatedAt: createdAt},
+					},
+					NextCursor: "next",
+				}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"transactions":[{"id":7,"direction":"sent","fromUser":"testuser","toUser":"bob","amount":15,` +
+				`"createdAt":"2025-02-01T12:00:00Z"}],"nextCursor":"next"}`,
+		},
+		{
+			name:  "Refunds",
+			query: "?direction=refund",
+			mock: func() {
+				mockService.On("History", mock.Anything, validUserID, domain.HistoryRequest{Direction: "refund"}).Return(&domain.HistoryResponse{
+					Transactions: []domain.HistoryEntry{
+						{ID: 3, Direction: "refund", ToUser: "testuser", Amount: 300, OrderID: 42, CreatedAt: createdAt},
+					},
+				}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"transactions":[{"id":3,"direction":"refund","fromUser":"","toUser":"testuser","amount":300,"orderId":42,` +
+				`"createdAt":"2025-02-01T12:00:00Z"}]}`,
+		},
+		{
+			name:  "Payment Requests",
+			query: "?direction=request",
+			mock: func() {
+				mockService.On("History", mock.Anything, validUserID, domain.HistoryRequest{Direction: "request"}).Return(&domain.HistoryResponse{
+					Transactions: []domain.HistoryEntry{
+						{ID: 4, Direction: "request", FromUser: "payer", ToUser: "testuser", Amount: 30, Message: "lunch", Status: "declined", CreatedAt: createdAt},
+					},
+				}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"transactions":[{"id":4,"direction":"request","fromUser":"payer","toUser":"testuser","amount":30,"message":"lunch",` +
+				`"status":"declined","createdAt":"2025-02-01T12:00:00Z"}]}`,
+		},
+		{
+			name:           "Malformed Query",
+			query:          "?limit=abc",
+			mock:           func() {},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid history filter","code":"invalid_history_filter"}`,
+		},
+		{
+			name:  "Invalid Cursor",
+			query: "?cursor=garbage",
+			mock: func() {
+				mockService.On("History", mock.Anything, validUserID, domain.HistoryRequest{Cursor: "garbage"}).
+					Return(nil, domain.ErrInvalidCursor).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid cursor","code":"invalid_cursor"}`,
+		},
+		{
+			name:  "Internal Server Error",
+			query: "",
+			mock: func() {
+				mockService.On("History", mock.Anything, validUserID, domain.HistoryRequest{}).
+					Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			req := httptest.NewRequest(http.MethodGet, "/history"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
+
+func TestTransactionHandler_Orders(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Get("/orders", handler.Orders())
+
+	createdAt := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "Success",
+			query: "?limit=1",
+			mock: func() {
+				mockService.On("Orders", mock.Anything, validUserID, domain.OrdersRequest{Limit: 1}).Return(&domain.OrdersResponse{
+					Orders: []domain.Order{
+						{
+							ID:        3,
+							Lines:     []domain.OrderLine{{ItemType: "cup", Quantity: 1, UnitPrice: 20, Total: 20}},
+							Total:     20,
+							CreatedAt: createdAt,
+						},
+					},
+					NextCursor: "next",
+				}, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody: `{"orders":[{"id":3,"lines":[{"itemType":"cup","quantity":1,"unitPrice":20,"total":20}],` +
+				`"total":20,"createdAt":"2025-02-01T12:00:00Z"}],"nextCursor":"next"}`,
+		},
+		{
+			name:  "Invalid Cursor",
+			query: "?cursor=garbage",
+			mock: func() {
+				mockService.On("Orders", mock.Anything, validUserID, domain.OrdersRequest{Cursor: "garbage"}).
+					Return(nil, domain.ErrInvalidCursor).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid cursor","code":"invalid_cursor"}`,
+		},
+		{
+			name:  "Internal Server Error",
+			query: "",
+			mock: func() {
+				mockService.On("Orders", mock.Anything, validUserID, domain.OrdersRequest{}).
+					Return(nil, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			req := httptest.NewRequest(http.MethodGet, "/orders"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(body))
+		})
+	}
+}
+
+func TestTransactionHandler_Checkout(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Post("/checkout", handler.Checkout())
+
+	cart := domain.CheckoutRequest{Items: []domain.CartLine{{Type: "cup", Quantity: 2}, {Type: "pen", Quantity: 1}}}
+	receipt := &domain.Order{
+		ID: 9,
+		Lines: []domain.OrderLine{
+			{ItemType: "cup", Quantity: 2, UnitPrice: 20, Total: 40},
+			{ItemType: "pen", Quantity: 1, UnitPrice: 10, Total: 10},
+		},
+		Total:     50,
+		CreatedAt: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC),
+	}
+	receiptBody := `{"id":9,"lines":[{"itemType":"cup","quantity":2,"unitPrice":20,"total":40},` +
+		`{"itemType":"pen","quantity":1,"unitPrice":10,"total":10}],"total":50,"createdAt":"2025-02-01T12:00:00Z"}`
+
+	tests := []struct {
+		name             string
+		idempotencyKey   string
+		mock             func()
+		expectedStatus   int
+		expectedBody     string
+		expectedReplayed string
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mockService.On("Checkout", mock.Anything, validUserID, cart, "").Return(receipt, false, nil).Once()
+			},
+			expectedStatus: fiber.StatusCreated,
+			expectedBody:   receiptBody,
+		},
+		{
+			name:           "Replayed",
+			idempotencyKey: "key-1",
+			mock: func() {
+				mockService.On("Checkout", mock.Anything, validUserID, cart, "key-1").Return(receipt, true, nil).Once()
+			},
+			expectedStatus:   fiber.StatusCreated,
+			expectedBody:     receiptBody,
+			expectedReplayed: "true",
+		},
+		{
+			name: "Invalid Cart",
+			mock: func() {
+				mockService.On("Checkout", mock.Anything, validUserID, cart, "").Return(nil, false, domain.ErrInvalidCart).Once()
+			},
+			expectedStatus: fiber.StatusBadRequest,
+			expectedBody:   `{"errors":"cart must hold 1-50 lines with quantities of 1-100","code":"invalid_cart"}`,
+		},
+		{
+			name: "Unknown Item",
+			mock: func() {
+				mockService.On("Checkout", mock.Anything, validUserID, cart, "").Return(nil, false, domain.ErrItemNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"item not found","code":"item_not_found"}`,
+		},
+		{
+			name: "Insufficient Funds",
+			mock: func() {
+				mockService.On("Checkout", mock.Anything, validUserID, cart, "").Return(nil, false, domain.ErrInsufficientFunds).Once()
+			},
+			expectedStatus: fiber.StatusPaymentRequired,
+			expectedBody:   `{"errors":"insufficient funds","code":"insufficient_funds"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			body, _ := json.Marshal(cart)
+			req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.idempotencyKey != "" {
+				req.Header.Set(domain.IdempotencyKeyHeader, tt.idempotencyKey)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			require.Equal(t, tt.expectedReplayed, resp.Header.Get("Idempotent-Replayed"))
+
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(respBody))
+		})
+	}
+}
+
+func TestTransactionHandler_Return(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app, group, validUserID, token := newAuthorizedApp(t)
+
+	group.Post("/return", handler.Return())
+
+	ret := domain.ReturnRequest{OrderID: 9, Type: "t-shirt-sized", Variant: "t-shirt-M"}
+	refund := &domain.Refund{
+		ID:        4,
+		OrderID:   9,
+		ItemType:  "t-shirt-sized",
+		Variant:   "t-shirt-M",
+		Quantity:  1,
+		Amount:    80,
+		CreatedAt: time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC),
+	}
+	refundBody := `{"id":4,"orderId":9,"itemType":"t-shirt-sized","variant":"t-shirt-M","quantity":1,"amount":80,"createdAt":"2025-02-03T09:00:00Z"}`
+
+	tests := []struct {
+		name             string
+		idempotencyKey   string
+		mock             func()
+		expectedStatus   int
+		expectedBody     string
+		expectedReplayed string
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "").Return(refund, false, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedBody:   refundBody,
+		},
+		{
+			name:           "Replayed",
+			idempotencyKey: "key-1",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "key-1").Return(refund, true, nil).Once()
+			},
+			expectedStatus:   fiber.StatusOK,
+			expectedBody:     refundBody,
+			expectedReplayed: "true",
+		},
+		{
+			name: "Order Not Found",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "").Return(nil, false, domain.ErrOrderNotFound).Once()
+			},
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody:   `{"errors":"order not found","code":"order_not_found"}`,
+		},
+		{
+			name: "Return Window Closed",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "").Return(nil, false, domain.ErrReturnWindowClosed).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"return window has closed","code":"return_window_closed"}`,
+		},
+		{
+			name: "Picked Up",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "").Return(nil, false, domain.ErrOrderPickedUp).Once()
+			},
+			expectedStatus: fiber.StatusConflict,
+			expectedBody:   `{"errors":"order has already been picked up","code":"order_picked_up"}`,
+		},
+		{
+			name: "Internal Server Error",
+			mock: func() {
+				mockService.On("Return", mock.Anything, validUserID, ret, "").Return(nil, false, errors.New("db error")).Once()
+			},
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedBody:   `{"errors":"internal server error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			body, _ := json.Marshal(ret)
+			req := httptest.NewRequest(http.MethodPost, "/return", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			if tt.idempotencyKey != "" {
+				req.Header.Set(domain.IdempotencyKeyHeader, tt.idempotencyKey)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			require.Equal(t, tt.expectedReplayed, resp.Header.Get("Idempotent-Replayed"))
+
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedBody, string(respBody))
+		})
+	}
+}
+
+func TestTransactionHandler_Idempotency(t *testing.T) {
+	mockService := new(MockTransactionService)
+
+	handler := NewTransaction(mockService)
+	app := fiber.New()
+
+	validUserID := uuid.New().String()
+	withUser := func(ctx fiber.Ctx) error {
+		ctx.Locals("id", validUserID)
+		return ctx.Next()
+	}
+
+	app.Post("/sendCoin", handler.Send(), withUser)
+	app.Get("/buy/:item", handler.Buy(), withUser)
+	app.Post("/gift", handler.Gift(), withUser)
+
+	replayed := &domain.StoredResponse{StatusCode: fiber.StatusOK}
+
+	tests := []struct {
+		name             string
+		request          func() *http.Request
+		mock             func()
+		expectedStatus   int
+		expectedReplayed string
+	}{
+		{
+			name: "Send Replay",
+			request: func() *http.Request {
+				body, _ := json.Marshal(domain.SendCoinRequest{ToUser: "test_user", Amount: 10})
+				req := httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+				return req
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 10}, "key-1").
+					Return(replayed, nil).Once()
+			},
+			expectedStatus:   fiber.StatusOK,
+			expectedReplayed: "true",
+		},
+		{
+			name: "Send First Use",
+			request: func() *http.Request {
+				body, _ := json.Marshal(domain.SendCoinRequest{ToUser: "test_user", Amount: 10})
+				req := httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-3")
+				return req
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 10}, "key-3").
+					Return(nil, nil).Once()
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "Send Key Mismatch",
+			request: func() *http.Request {
+				body, _ := json.Marshal(domain.SendCoinRequest{ToUser: "test_user", Amount: 20})
+				req := httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+				return req
+			},
+			mock: func() {
+				mockService.On("Send", mock.Anything, validUserID, domain.SendCoinRequest{ToUser: "test_user", Amount: 20}, "key-1").
+					Return(nil, domain.ErrIdempotencyKeyMismatch).Once()
+			},
+			expectedStatus: fiber.StatusUnprocessableEntity,
+		},
+		{
+			name: "Buy Replay",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/buy/cup", nil)
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-2")
+				return req
+			},
+			mock: func() {
+				mockService.On("Buy", mock.Anything, validUserID, "cup", "", "key-2").Return(replayed, nil).Once()
+			},
+			expectedStatus:   fiber.StatusOK,
+			expectedReplayed: "true",
+		},
+		{
+			name: "Gift Replay",
+			request: func() *http.Request {
+				body, _ := json.Marshal(domain.GiftRequest{ToUser: "friend", Type: "cup"})
+				req := httptest.NewRequest(http.MethodPost, "/gift", bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-4")
+				return req
+			},
+			mock: func() {
+				mockService.On("Gift", mock.Anything, validUserID, domain.GiftRequest{ToUser: "friend", Type: "cup"}, "key-4").
+					Return(replayed, nil).Once()
+			},
+			expectedStatus:   fiber.StatusOK,
+			expectedReplayed: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			resp, err := app.Test(tt.request())
+			require.NoError(t, err)
 
 			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			require.Equal(t, tt.expectedReplayed, resp.Header.Get("Idempotent-Replayed"))
+			mockService.AssertExpectations(t)
 		})
 	}
 }
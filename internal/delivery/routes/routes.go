@@ -4,20 +4,126 @@ import "github.com/gofiber/fiber/v3"
 
 type AuthHandler interface {
 	Auth() fiber.Handler
+	Register() fiber.Handler
+	Login() fiber.Handler
+	Refresh() fiber.Handler
+	Logout() fiber.Handler
+}
+
+type KeysHandler interface {
+	JWKS() fiber.Handler
+}
+
+type RoleHandler interface {
+	Grant() fiber.Handler
+	Revoke() fiber.Handler
+}
+
+type CatalogHandler interface {
+	List() fiber.Handler
+	Get() fiber.Handler
+}
+
+type AdminCatalogHandler interface {
+	Create() fiber.Handler
+	Update() fiber.Handler
+	Retire() fiber.Handler
+	Restock() fiber.Handler
+	CreateVariant() fiber.Handler
+	PriceHistory() fiber.Handler
+}
+
+type FulfilmentHandler interface {
+	Open() fiber.Handler
+}
+
+type AdminFulfilmentHandler interface {
+	List() fiber.Handler
+	Advance() fiber.Handler
+}
+
+type PaymentRequestHandler interface {
+	Create() fiber.Handler
+	Incoming() fiber.Handler
+	Sent() fiber.Handler
+	Approve() fiber.Handler
+	Decline() fiber.Handler
+}
+
+type KudosHandler interface {
+	Feed() fiber.Handler
 }
 
 type TransactionHandler interface {
 	Buy() fiber.Handler
 	Send() fiber.Handler
 	Info() fiber.Handler
+	History() fiber.Handler
+	Orders() fiber.Handler
+	Checkout() fiber.Handler
+	Gift() fiber.Handler
+	Return() fiber.Handler
 }
 
-func MapAuthRoutes(r fiber.Router, h AuthHandler) {
+func MapAuthRoutes(r fiber.Router, h AuthHandler, authMiddleware fiber.Handler) {
 	r.Post(`/auth`, h.Auth())
+	r.Post(`/register`, h.Register())
+	r.Post(`/login`, h.Login())
+	r.Post(`/auth/refresh`, h.Refresh())
+	r.Post(`/auth/logout`, h.Logout(), authMiddleware)
+}
+
+func MapWellKnownRoutes(r fiber.Router, h KeysHandler) {
+	r.Get(`/jwks.json`, h.JWKS())
+}
+
+func MapAdminRoutes(r fiber.Router, h RoleHandler) {
+	r.Post(`/users/:username/roles`, h.Grant())
+	r.Delete(`/users/:username/roles/:role`, h.Revoke())
+}
+
+func MapAdminCatalogRoutes(r fiber.Router, h AdminCatalogHandler) {
+	r.Post(`/items`, h.Create())
+	r.Patch(`/items/:type`, h.Update())
+	r.Delete(`/items/:type`, h.Retire())
+	r.Post(`/items/:type/restock`, h.Restock())
+	r.Post(`/items/:type/variants`, h.CreateVariant())
+	r.Get(`/items/:type/prices`, h.PriceHistory())
+}
+
+func MapCatalogRoutes(r fiber.Router, h CatalogHandler) {
+	r.Get(`/`, h.List())
+	r.Get(`/:type`, h.Get())
 }
 
 func MapTransactionRoutes(r fiber.Router, h TransactionHandler) {
 	r.Get(`/info`, h.Info())
+	r.Get(`/history`, h.History())
+	r.Get(`/orders`, h.Orders())
 	r.Get(`/buy/:item`, h.Buy())
 	r.Post(`/sendCoin`, h.Send())
+	r.Post(`/checkout`, h.Checkout())
+	r.Post(`/gift`, h.Gift())
+	r.Post(`/return`, h.Return())
+}
+
+func MapFulfilmentRoutes(r fiber.Router, h FulfilmentHandler) {
+	r.Get(`/orders/open`, h.Open())
+}
+
+func MapAdminFulfilmentRoutes(r fiber.Router, h AdminFulfilmentHandler) {
+	r.Get(`/orders`, h.List())
+	r.Post(`/orders/:id/status`, h.Advance())
+}
+
+func MapPaymentRequestRoutes(r fiber.Router, h PaymentRequestHandler) {
+	r.Post(`/requests`, h.Create())
+	r.Get(`/requests/incoming`, h.Incoming())
+	r.Get(`/requests/sent`, h.Sent())
+	r.Post(`/requests/:id/approve`, h.Approve())
+	r.Post(`/requests/:id/decline`, h.Decline())
+}
+
+func MapKudosRoutes(r fiber.Router, h KudosHandler) {
+	r.Get(`/`, h.Feed())
 }
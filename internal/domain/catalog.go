@@ -0,0 +1,83 @@
+package domain
+
+import "time"
+
+// CatalogItem omits stock for items sold in unlimited supply.
+type CatalogItem struct {
+	Type              string     `json:"type"`
+	Price             int64      `json:"price"`
+	Description       string     `json:"description"`
+	Category          string     `json:"category"`
+	Available         bool       `json:"available"`
+	Stock             *int       `json:"stock,omitempty"`
+	LowStockThreshold *int       `json:"lowStockThreshold,omitempty"`
+	RetiredAt         *time.Time `json:"retiredAt,omitempty"`
+	Variants          []Variant  `json:"variants,omitempty"`
+}
+
+// Variant carries its effective price; stock is omitted when the variant
+// draws on the item's stock or the supply is unlimited.
+type Variant struct {
+	Sku   string `json:"sku"`
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+	Price int64  `json:"price"`
+	Stock *int   `json:"stock,omitempty"`
+}
+
+type CatalogResponse struct {
+	Items []CatalogItem `json:"items"`
+}
+
+// CreateItemRequest creates an item in unlimited supply unless Stock is set.
+type CreateItemRequest struct {
+	Type              string `json:"type"`
+	Price             int64  `json:"price"`
+	Description       string `json:"description"`
+	Category          string `json:"category"`
+	Available         *bool  `json:"available"`
+	Stock             *int   `json:"stock"`
+	LowStockThreshold *int   `json:"lowStockThreshold"`
+}
+
+// UpdateItemRequest changes only the fields that are present in the body.
+type UpdateItemRequest struct {
+	Price             *int64  `json:"price"`
+	Description       *string `json:"description"`
+	Category          *string `json:"category"`
+	Available         *bool   `json:"available"`
+	LowStockThreshold *int    `json:"lowStockThreshold"`
+}
+
+// CreateVariantRequest leaves Price and Stock unset to use the item's.
+type CreateVariantRequest struct {
+	Sku   string `json:"sku"`
+	Size  string `json:"size"`
+	Color string `json:"color"`
+	Price *int64 `json:"price"`
+	Stock *int   `json:"stock"`
+}
+
+// RestockRequest restocks a single variant when Variant is set.
+type RestockRequest struct {
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+type PriceChange struct {
+	Change            string    `json:"change"`
+	Variant           string    `json:"variant,omitempty"`
+	Price             int64     `json:"price"`
+	Description       string    `json:"description"`
+	Category          string    `json:"category"`
+	Available         bool      `json:"available"`
+	Stock             *int      `json:"stock,omitempty"`
+	LowStockThreshold *int      `json:"lowStockThreshold,omitempty"`
+	ChangedBy         string    `json:"changedBy,omitempty"`
+	ChangedAt         time.Time `json:"changedAt"`
+}
+
+type PriceHistoryResponse struct {
+	Type    string        `json:"type"`
+	Changes []PriceChange `json:"changes"`
+}
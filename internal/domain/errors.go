@@ -5,8 +5,103 @@ import "errors"
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserAlreadyExists  = errors.New("user already exists")
+	ErrInvalidRole        = errors.New("invalid role")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+
+	ErrInvalidIdempotencyKey  = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key reused with a different request")
+
+	ErrInsufficientFunds      = errors.New("insufficient funds")
+	ErrRecipientNotFound      = errors.New("recipient not found")
+	ErrItemNotFound           = errors.New("item not found")
+	ErrItemUnavailable        = errors.New("item unavailable")
+	ErrItemRetired            = errors.New("item retired")
+	ErrOutOfStock             = errors.New("out of stock")
+	ErrVariantRequired        = errors.New("variant required")
+	ErrVariantNotFound        = errors.New("variant not found")
+	ErrVariantAlreadyExists   = errors.New("variant already exists")
+	ErrItemAlreadyExists      = errors.New("item already exists")
+	ErrInvalidItem            = errors.New("invalid item")
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidCart            = errors.New("invalid cart")
+	ErrInvalidAmount          = errors.New("amount must be positive")
+	ErrSelfTransfer           = errors.New("cannot send coins to yourself")
+	ErrInvalidKudos           = errors.New("invalid kudos")
+	ErrInvalidPaymentRequest  = errors.New("invalid payment request")
+	ErrSelfPaymentRequest     = errors.New("cannot request coins from yourself")
+	ErrPaymentRequestNotFound = errors.New("payment request not found")
+	ErrPaymentRequestResolved = errors.New("payment request already resolved")
+	ErrPaymentRequestExpired  = errors.New("payment request expired")
+	ErrInvalidPaymentStatus   = errors.New("invalid payment request status")
+	ErrSelfGift               = errors.New("cannot send a gift to yourself")
+	ErrNotEnoughItems         = errors.New("not enough items in inventory")
+	ErrInvalidGift            = errors.New("invalid gift")
+	ErrInvalidReturn          = errors.New("invalid return")
+	ErrRefundNotFound         = errors.New("refund not found")
+	ErrReturnWindowClosed     = errors.New("return window closed")
+	ErrOrderPickedUp          = errors.New("order already picked up")
+	ErrGiftNotReturnable      = errors.New("gifts cannot be returned")
+	ErrOrderCancelled         = errors.New("order cancelled")
+	ErrInvalidOrderStatus     = errors.New("invalid order status")
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidHistoryFilter = errors.New("invalid history filter")
+)
+
+// Machine-readable error codes returned in ErrorResponse.Code.
+const (
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeUnauthorized           = "unauthorized"
+	CodeInvalidInput           = "invalid_input"
+	CodeInvalidIdempotencyKey  = "invalid_idempotency_key"
+	CodeIdempotencyKeyMismatch = "idempotency_key_mismatch"
+	CodeInsufficientFunds      = "insufficient_funds"
+	CodeRecipientNotFound      = "recipient_not_found"
+	CodeItemNotFound           = "item_not_found"
+	CodeItemUnavailable        = "item_unavailable"
+	CodeItemRetired            = "item_retired"
+	CodeOutOfStock             = "out_of_stock"
+	CodeVariantRequired        = "variant_required"
+	CodeVariantNotFound        = "variant_not_found"
+	CodeVariantAlreadyExists   = "variant_already_exists"
+	CodeItemAlreadyExists      = "item_already_exists"
+	CodeInvalidItem            = "invalid_item"
+	CodeOrderNotFound          = "order_not_found"
+	CodeInvalidCart            = "invalid_cart"
+	CodeInvalidAmount          = "invalid_amount"
+	CodeSelfTransfer           = "self_transfer"
+	CodeInvalidKudos           = "invalid_kudos"
+	CodeUserNotFound           = "user_not_found"
+	CodeInvalidPaymentRequest  = "invalid_payment_request"
+	CodeSelfPaymentRequest     = "self_payment_request"
+	CodePaymentRequestNotFound = "payment_request_not_found"
+	CodePaymentRequestResolved = "payment_request_resolved"
+	CodePaymentRequestExpired  = "payment_request_expired"
+	CodeInvalidPaymentStatus   = "invalid_payment_request_status"
+	CodeSelfGift               = "self_gift"
+	CodeNotEnoughItems         = "not_enough_items"
+	CodeInvalidGift            = "invalid_gift"
+	CodeInvalidReturn          = "invalid_return"
+	CodeRefundNotFound         = "refund_not_found"
+	CodeReturnWindowClosed     = "return_window_closed"
+	CodeOrderPickedUp          = "order_picked_up"
+	CodeGiftNotReturnable      = "gift_not_returnable"
+	CodeOrderCancelled         = "order_cancelled"
+	CodeInvalidOrderStatus     = "invalid_order_status"
+	CodeInvalidOrderTransition = "invalid_order_transition"
+	CodeInvalidCursor          = "invalid_cursor"
+	CodeInvalidHistoryFilter   = "invalid_history_filter"
+	CodeInternal               = "internal_error"
 )
 
 type ErrorResponse struct {
 	Errors string `json:"errors"`
+	Code   string `json:"code,omitempty"`
 }
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	GiftSourcePurchase  = "purchase"
+	GiftSourceInventory = "inventory"
+)
+
+// GiftRequest buys the item for the recipient by default; with source
+// "inventory" it hands over items the sender already owns. Quantity
+// defaults to 1.
+type GiftRequest struct {
+	ToUser   string `json:"toUser"`
+	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+type Gift struct {
+	FromUser  string    `json:"fromUser,omitempty"`
+	ToUser    string    `json:"toUser,omitempty"`
+	Type      string    `json:"type"`
+	Variant   string    `json:"variant,omitempty"`
+	Quantity  int       `json:"quantity"`
+	Source    string    `json:"source"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
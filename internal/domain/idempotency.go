@@ -0,0 +1,10 @@
+package domain
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// StoredResponse is the response recorded for an idempotency key and
+// returned again on replays.
+type StoredResponse struct {
+	StatusCode int
+	Body       []byte
+}
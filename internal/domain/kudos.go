@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Kudos leaves out the amount: the feed shows who thanked whom, not for how much.
+type Kudos struct {
+	ID        int64     `json:"id"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Category  string    `json:"category,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type KudosRequest struct {
+	Category string `query:"category"`
+	Cursor   string `query:"cursor"`
+	Limit    int    `query:"limit"`
+}
+
+type KudosResponse struct {
+	Kudos      []Kudos `json:"kudos"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
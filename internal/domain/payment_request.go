@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// CreatePaymentRequest asks FromUser to send Amount coins to the caller.
+type CreatePaymentRequest struct {
+	FromUser string `json:"fromUser"`
+	Amount   int    `json:"amount"`
+	Message  string `json:"message,omitempty"`
+}
+
+// PaymentRequest follows the direction of the coins: FromUser is asked to pay,
+// ToUser made the request.
+type PaymentRequest struct {
+	ID         int64      `json:"id"`
+	FromUser   string     `json:"fromUser"`
+	ToUser     string     `json:"toUser"`
+	Amount     int        `json:"amount"`
+	Message    string     `json:"message,omitempty"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// PaymentRequestsRequest pages through payment requests; Status is one of
+// pending, approved, declined or expired and only applies to sent requests.
+type PaymentRequestsRequest struct {
+	Status string `query:"status"`
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+type PaymentRequestsResponse struct {
+	Requests   []PaymentRequest `json:"requests"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// ReturnRequest hands back items bought on one line of an order, named by the
+// line's item type and variant. Quantity defaults to 1.
+type ReturnRequest struct {
+	OrderID  int64  `json:"orderId"`
+	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity,omitempty"`
+}
+
+type Refund struct {
+	ID        int64     `json:"id"`
+	OrderID   int64     `json:"orderId"`
+	ItemType  string    `json:"itemType"`
+	Variant   string    `json:"variant,omitempty"`
+	Quantity  int       `json:"quantity"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+}
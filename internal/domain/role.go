@@ -0,0 +1,25 @@
+package domain
+
+const (
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+)
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+type RolesResponse struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAdmin, RoleAuditor:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,7 +1,10 @@
 package domain
 
+import "time"
+
 type Item struct {
 	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
 	Quantity int    `json:"quantity"`
 }
 
@@ -16,13 +19,116 @@ type CoinTransaction struct {
 	Amount   int    `json:"amount"`
 }
 
+// SendCoinRequest may thank the recipient with a message and a kudos category.
+// Unless Private is set, the note shows up in the public kudos feed.
 type SendCoinRequest struct {
-	ToUser string `json:"toUser"`
-	Amount int    `json:"amount"`
+	ToUser   string `json:"toUser"`
+	Amount   int    `json:"amount"`
+	Message  string `json:"message,omitempty"`
+	Category string `json:"category,omitempty"`
+	Private  bool   `json:"private,omitempty"`
 }
 
 type InfoResponse struct {
-	Coins       int         `json:"coins"`
-	Inventory   []Item      `json:"inventory"`
-	CoinHistory CoinHistory `json:"coinHistory"`
+	Coins           int         `json:"coins"`
+	Inventory       []Item      `json:"inventory"`
+	CoinHistory     CoinHistory `json:"coinHistory"`
+	RecentPurchases []Order     `json:"recentPurchases"`
+	Gifted          []Gift      `json:"gifted"`
+	ReceivedGifts   []Gift      `json:"receivedGifts"`
+	Refunds         []Refund    `json:"refunds"`
+}
+
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+	DirectionRefund   = "refund"
+	DirectionRequest  = "request"
+)
+
+// HistoryRequest filters the coin history. Zero values mean "no filter";
+// From and To are RFC 3339 timestamps.
+type HistoryRequest struct {
+	Direction    string `query:"direction"`
+	Counterparty string `query:"counterparty"`
+	MinAmount    int    `query:"minAmount"`
+	MaxAmount    int    `query:"maxAmount"`
+	From         string `query:"from"`
+	To           string `query:"to"`
+	Cursor       string `query:"cursor"`
+	Limit        int    `query:"limit"`
+}
+
+// HistoryEntry is a transfer, with Direction "refund" a refund of part of order
+// OrderID, or with Direction "request" a payment request FromUser declined or
+// let expire, as Status tells. ID is the transfer, refund or request id.
+type HistoryEntry struct {
+	ID        int64     `json:"id"`
+	Direction string    `json:"direction"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Private   bool      `json:"private,omitempty"`
+	OrderID   int64     `json:"orderId,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type HistoryResponse struct {
+	Transactions []HistoryEntry `json:"transactions"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+}
+
+// Order doubles as the itemised checkout receipt.
+type Order struct {
+	ID        int64       `json:"id"`
+	User      string      `json:"user,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	Lines     []OrderLine `json:"lines"`
+	Total     int64       `json:"total"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+type OrderLine struct {
+	ItemType  string `json:"itemType"`
+	Variant   string `json:"variant,omitempty"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unitPrice"`
+	Total     int64  `json:"total"`
+	Returned  int    `json:"returned,omitempty"`
+}
+
+// CartLine names a variant by its SKU; items that have variants require one.
+type CartLine struct {
+	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+type CheckoutRequest struct {
+	Items []CartLine `json:"items"`
+}
+
+type OrdersRequest struct {
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+// OrderStatusRequest moves an order to created, ready, picked_up or cancelled.
+type OrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// AdminOrdersRequest lists orders in one status, or all open ones by default.
+type AdminOrdersRequest struct {
+	Status string `query:"status"`
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+type OrdersResponse struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"nextCursor,omitempty"`
 }
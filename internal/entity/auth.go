@@ -9,5 +9,6 @@ type Auth struct {
 	Id        uuid.UUID
 	Username  string
 	Password  string
+	Roles     []string
 	CreatedAt time.Time
 }
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ItemChangeCreate  = "create"
+	ItemChangeUpdate  = "update"
+	ItemChangeRetire  = "retire"
+	ItemChangeRestock = "restock"
+)
+
+// CatalogItem is on sale in unlimited supply unless Stock is set.
+type CatalogItem struct {
+	Id                uuid.UUID
+	Type              string
+	Price             int64
+	Description       string
+	Category          string
+	Available         bool
+	Stock             *int
+	LowStockThreshold *int
+	RetiredAt         *time.Time
+	UpdatedAt         time.Time
+	Variants          []ItemVariant `db:"-"`
+}
+
+// ItemVariant is a purchasable version of an item, such as a size or colour.
+// A nil Price falls back to the item's price and a nil Stock draws on the
+// item's stock.
+type ItemVariant struct {
+	Id        uuid.UUID
+	ItemId    uuid.UUID
+	Sku       string
+	Size      string
+	Color     string
+	Price     *int64
+	Stock     *int
+	CreatedAt time.Time
+}
+
+// ItemUpdate holds the fields an admin wants to change; nil fields are kept.
+type ItemUpdate struct {
+	Price             *int64
+	Description       *string
+	Category          *string
+	Available         *bool
+	LowStockThreshold *int
+}
+
+// PriceChange is a snapshot of an item taken after each admin change.
+type PriceChange struct {
+	Change            string
+	Variant           string
+	Price             int64
+	Description       string
+	Category          string
+	Available         bool
+	Stock             *int
+	LowStockThreshold *int
+	ChangedBy         *uuid.UUID
+	ChangedAt         time.Time
+}
+
+// StockLevel reports an item whose stock has fallen to its low-stock threshold.
+type StockLevel struct {
+	ItemType  string
+	Variant   string
+	Stock     int
+	Threshold int
+}
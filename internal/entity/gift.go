@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+const (
+	GiftSourcePurchase  = "purchase"
+	GiftSourceInventory = "inventory"
+)
+
+// Gift is an item given to another user, either bought for them on the spot or
+// moved from the sender's inventory. FromUser and ToUser are usernames.
+type Gift struct {
+	Id        int64
+	FromUser  string
+	ToUser    string
+	ItemType  string
+	Variant   string
+	Quantity  int
+	Source    string
+	OrderId   *int64
+	Message   string
+	CreatedAt time.Time
+}
+
+type SendGift struct {
+	ToUser   string
+	ItemType string
+	Variant  string
+	Quantity int
+	Source   string
+	Message  string
+}
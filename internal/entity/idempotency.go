@@ -0,0 +1,11 @@
+package entity
+
+import "github.com/google/uuid"
+
+type IdempotencyKey struct {
+	UserId      uuid.UUID
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Response    []byte
+}
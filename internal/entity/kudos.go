@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Kudos categories a transfer can be tagged with.
+const (
+	KudosHelpedMe    = "helped_me"
+	KudosGreatReview = "great_review"
+	KudosOnboarding  = "onboarding"
+	KudosTeamwork    = "teamwork"
+)
+
+// Kudos is a public thank-you: a transfer that is not private and carries a
+// message or a category.
+type Kudos struct {
+	Id        int64
+	FromUser  string
+	ToUser    string
+	Message   string
+	Category  string
+	CreatedAt time.Time
+}
+
+type KudosFilter struct {
+	Category string
+	After    *PageCursor
+	Limit    int
+}
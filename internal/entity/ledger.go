@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	LedgerAccountUser     = "user"
+	LedgerAccountRevenue  = "revenue"
+	LedgerAccountIssuance = "issuance"
+
+	JournalOpeningBalance = "opening_balance"
+	JournalGrant          = "grant"
+	JournalTransfer       = "transfer"
+	JournalPurchase       = "purchase"
+	JournalRefund         = "refund"
+	JournalAdjustment     = "adjustment"
+)
+
+var (
+	// RevenueAccountID collects coins spent in the merch store.
+	RevenueAccountID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	// IssuanceAccountID is the source of every coin granted to users; its balance is negative.
+	IssuanceAccountID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+// Posting moves Amount coins into (positive) or out of (negative) an account.
+// User accounts share the user's id.
+type Posting struct {
+	AccountId uuid.UUID
+	Amount    int64
+}
+
+type JournalEntry struct {
+	Id        int64
+	Kind      string
+	Reference string
+	Postings  []Posting
+	CreatedAt time.Time
+}
+
+// BalanceDrift is a user whose cached users.coin or ledger balance disagrees with
+// the balance recomputed from the source tables. Expected = Granted + Received -
+// Sent - Spent, where Received and Sent come from coin_transactions and Spent from
+// orders net of refunds. Granted is read from the ledger, the only record of grants.
+// Drift = Cached - Expected.
+type BalanceDrift struct {
+	UserId   uuid.UUID `json:"userId"`
+	Username string    `json:"username"`
+	Cached   int64     `json:"cached"`
+	Ledger   int64     `json:"ledger"`
+	Granted  int64     `json:"granted"`
+	Received int64     `json:"received"`
+	Sent     int64     `json:"sent"`
+	Spent    int64     `json:"spent"`
+	Expected int64     `json:"expected"`
+	Drift    int64     `json:"drift"`
+}
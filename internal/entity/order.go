@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// An order moves from created to ready to picked_up as the office hands the
+// merch out, or is cancelled and refunded before that.
+const (
+	OrderCreated   = "created"
+	OrderReady     = "ready"
+	OrderPickedUp  = "picked_up"
+	OrderCancelled = "cancelled"
+)
+
+// Order audit actions name the endpoint that placed the order.
+const (
+	OrderAuditBuy      = "buy"
+	OrderAuditCheckout = "checkout"
+)
+
+type Order struct {
+	Id     int64
+	UserId uuid.UUID
+	// Username is only loaded for the admin order listing.
+	Username  string
+	Total     int64
+	Status    string
+	Lines     []OrderLine `db:"-"`
+	CreatedAt time.Time
+	// LowStock lists the items this order took down to their low-stock threshold.
+	// It is only set on orders that were just placed.
+	LowStock []StockLevel `db:"-"`
+}
+
+// OrderLine has an empty Variant for items sold without variants.
+type OrderLine struct {
+	OrderId   int64
+	ItemType  string
+	Variant   string
+	Quantity  int
+	UnitPrice int64
+	// Returned counts the items of the line that have been returned and refunded.
+	Returned int
+}
+
+// OrderFilter selects orders for the fulfilment listings. A nil UserId matches
+// every user's orders.
+type OrderFilter struct {
+	UserId   *uuid.UUID
+	Statuses []string
+	After    *PageCursor
+	Limit    int
+}
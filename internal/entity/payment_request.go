@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Payment request statuses. Expired is never stored: it is how a pending
+// request reads once its expires_at has passed.
+const (
+	PaymentRequestPending  = "pending"
+	PaymentRequestApproved = "approved"
+	PaymentRequestDeclined = "declined"
+	PaymentRequestExpired  = "expired"
+)
+
+// PaymentRequest asks Payer to send Amount coins to Requester.
+type PaymentRequest struct {
+	Id         int64
+	Requester  string
+	Payer      string
+	Amount     int
+	Message    string
+	Status     string
+	ExpiresAt  time.Time
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// NewPaymentRequest expires TTL after it is stored, measured on the database
+// clock that every expiry check uses.
+type NewPaymentRequest struct {
+	Payer   string
+	Amount  int
+	Message string
+	TTL     time.Duration
+}
+
+// PaymentRequestFilter lists the requests made by RequesterId or addressed to
+// PayerId; an empty Status matches every status.
+type PaymentRequestFilter struct {
+	RequesterId *uuid.UUID
+	PayerId     *uuid.UUID
+	Status      string
+	After       *PageCursor
+	Limit       int
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Refund pays back part of an order line at the unit price originally paid.
+type Refund struct {
+	Id        int64
+	OrderId   int64
+	ItemType  string
+	Variant   string
+	Quantity  int
+	Amount    int64
+	CreatedAt time.Time
+}
+
+// ReturnItem hands back Quantity items bought on an order line. Only orders
+// placed after PurchasedAfter are still within the return window.
+type ReturnItem struct {
+	OrderId        int64
+	ItemType       string
+	Variant        string
+	Quantity       int
+	PurchasedAfter time.Time
+}
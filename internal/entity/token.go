@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type RefreshToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	Username  string
+	FamilyId  uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RevokedToken struct {
+	Jti       string
+	ExpiresAt time.Time
+}
@@ -1,7 +1,10 @@
 package entity
 
+import "time"
+
 type Item struct {
 	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
 	Quantity int    `json:"quantity"`
 }
 
@@ -16,13 +19,67 @@ type CoinTransaction struct {
 	Amount   int    `json:"amount"`
 }
 
+// SendCoin may carry a note thanking the recipient. Notes of transfers that are
+// not Private appear in the public kudos feed.
 type SendCoin struct {
-	ToUser string `json:"toUser"`
-	Amount int    `json:"amount"`
+	ToUser   string `json:"toUser"`
+	Amount   int    `json:"amount"`
+	Message  string `json:"message"`
+	Category string `json:"category"`
+	Private  bool   `json:"private"`
 }
 
 type Info struct {
-	Coins       int         `json:"coins"`
-	Inventory   []Item      `json:"inventory"`
-	CoinHistory CoinHistory `json:"coinHistory"`
+	Coins           int         `json:"coins"`
+	Inventory       []Item      `json:"inventory"`
+	CoinHistory     CoinHistory `json:"coinHistory"`
+	RecentPurchases []Order     `json:"recentPurchases"`
+	Gifted          []Gift      `json:"gifted"`
+	ReceivedGifts   []Gift      `json:"receivedGifts"`
+	Refunds         []Refund    `json:"refunds"`
+}
+
+// History entries are transfers, refunds or payment requests; ids are only
+// unique within a kind.
+const (
+	HistoryTransfer       = "transfer"
+	HistoryRefund         = "refund"
+	HistoryPaymentRequest = "payment_request"
+)
+
+// HistoryEntry is a transfer, a refund for part of OrderId, or a payment request
+// FromUser declined or let expire. Status is only set for payment requests.
+type HistoryEntry struct {
+	Id        int64
+	Kind      string
+	Direction string
+	FromUser  string
+	ToUser    string
+	Amount    int
+	Message   string
+	Category  string
+	Private   bool
+	OrderId   int64
+	Status    string
+	CreatedAt time.Time
+}
+
+// PageCursor points at the last row of the previous page of a listing
+// ordered by (created_at, id) descending. Listings that mix several tables
+// order by (created_at, kind, id) and also set Kind.
+type PageCursor struct {
+	CreatedAt time.Time
+	Kind      string
+	Id        int64
+}
+
+type HistoryFilter struct {
+	Direction    string
+	Counterparty string
+	MinAmount    int
+	MaxAmount    int
+	From         time.Time
+	To           time.Time
+	After        *PageCursor
+	Limit        int
 }
@@ -0,0 +1,52 @@
+package event
+
+import (
+	"avito_test/internal/entity"
+	"avito_test/pkg/logger"
+	"context"
+	"sync"
+)
+
+type LowStockHandler func(ctx context.Context, level entity.StockLevel)
+
+// Bus delivers domain events to in-process subscribers. Handlers run in their
+// own goroutines, so a slow subscriber never holds up the purchase that
+// published the event.
+type Bus struct {
+	mu       sync.RWMutex
+	lowStock []LowStockHandler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) SubscribeLowStock(handler LowStockHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lowStock = append(b.lowStock, handler)
+}
+
+func (b *Bus) PublishLowStock(ctx context.Context, level entity.StockLevel) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range b.lowStock {
+		go handler(ctx, level)
+	}
+}
+
+// WarnLowStock logs every low-stock event as a warning, naming the variant's SKU
+// when the stock belongs to one.
+func WarnLowStock(logger *logger.ApiLogger) LowStockHandler {
+	return func(_ context.Context, level entity.StockLevel) {
+		if level.Variant != "" {
+			logger.Warnf("item %s variant %s is low on stock: %d left, threshold %d",
+				level.ItemType, level.Variant, level.Stock, level.Threshold)
+			return
+		}
+		logger.Warnf("item %s is low on stock: %d left, threshold %d", level.ItemType, level.Stock, level.Threshold)
+	}
+}
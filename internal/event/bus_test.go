@@ -0,0 +1,39 @@
+package event
+
+import (
+	"avito_test/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishLowStock(t *testing.T) {
+	bus := NewBus()
+
+	received := make(chan entity.StockLevel, 2)
+	for range 2 {
+		bus.SubscribeLowStock(func(_ context.Context, level entity.StockLevel) {
+			received <- level
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	level := entity.StockLevel{ItemType: "pink-hoody", Stock: 3, Threshold: 5}
+	bus.PublishLowStock(ctx, level)
+	cancel()
+
+	for range 2 {
+		select {
+		case got := <-received:
+			require.Equal(t, level, got)
+		case <-time.After(time.Second):
+			t.Fatal("low-stock event was not delivered")
+		}
+	}
+}
+
+func TestBus_PublishWithoutSubscribers(t *testing.T) {
+	NewBus().PublishLowStock(context.Background(), entity.StockLevel{ItemType: "cup"})
+}
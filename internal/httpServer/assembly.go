@@ -3,46 +3,125 @@ package httpServer
 import (
 	"avito_test/internal/delivery/handler"
 	"avito_test/internal/delivery/routes"
+	"avito_test/internal/domain"
+	"avito_test/internal/event"
 	"avito_test/internal/jwt"
 	"avito_test/internal/middleware"
 	"avito_test/internal/repository"
 	"avito_test/internal/service"
+	"avito_test/internal/worker"
+	"avito_test/pkg/hasher"
 	"avito_test/pkg/logger"
 	storage "avito_test/pkg/storage/postgres"
+	"context"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	serverLogger "github.com/gofiber/fiber/v3/middleware/logger"
+	"github.com/pkg/errors"
 )
 
 func (s *Server) MapHandlers(app *fiber.App, logger *logger.ApiLogger) error {
-	db, err := storage.InitPsqlDB(s.cfg)
+	db, err := storage.InitPsqlDB(s.cfg, logger)
 	if err != nil {
 		logger.Fatalf("failed to initialize PostgreSQL DB: %v", err)
 	}
 
 	jwtService := jwt.NewJWTService(s.cfg)
+	if s.cfg.Token.KeysDir != "" {
+		keyring, err := jwt.LoadKeyring(s.cfg.Token.KeysDir, s.cfg.Token.ActiveKID, s.cfg.Token.RotationWindow)
+		if err != nil {
+			logger.Fatalf("failed to load JWT keyring: %v", err)
+		}
+		jwtService = jwt.NewJWTServiceWithKeyring(s.cfg, keyring)
+	}
+	keysHandler := handler.NewKeys(jwtService)
+
+	passwordHasher, err := hasher.NewHasher(s.cfg)
+	if err != nil {
+		logger.Fatalf("failed to initialize password hasher: %v", err)
+	}
+
+	tokenRepo := repository.NewToken(db)
+	authCache := repository.NewMemoryAuthCache()
+	tokenSweeper := worker.NewRevokedTokenSweeper(tokenRepo, s.cfg.Token.SweepInterval, logger)
+	go tokenSweeper.Run(context.Background())
 
 	authRepo := repository.NewAuth(db)
-	authService := service.NewAuth(authRepo, jwtService)
+	authService := service.NewAuth(authRepo, tokenRepo, authCache, jwtService, passwordHasher, s.cfg.Token.RefreshTTL, logger)
 	authHandler := handler.NewAuth(authService)
 
+	roleService := service.NewRole(authRepo)
+	roleHandler := handler.NewRole(roleService)
+
+	if s.cfg.Admin.Username != "" {
+		_, err = roleService.Grant(context.Background(), s.cfg.Admin.Username, domain.RoleRequest{Role: domain.RoleAdmin})
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			logger.Warnf("admin user %s is not registered yet, register it and restart to grant the admin role", s.cfg.Admin.Username)
+		case err != nil:
+			logger.Fatalf("failed to grant the admin role to %s: %v", s.cfg.Admin.Username, err)
+		}
+	}
+
+	idempotencyRepo := repository.NewIdempotency(db)
+	idempotencySweeper := worker.NewIdempotencySweeper(idempotencyRepo, s.cfg.Idempotency.TTL, s.cfg.Idempotency.SweepInterval, logger)
+	go idempotencySweeper.Run(context.Background())
+
+	if s.cfg.Reconcile.Interval > 0 {
+		reconciler := worker.NewReconciler(repository.NewLedger(db), s.cfg.Reconcile.Interval, logger)
+		go reconciler.Run(context.Background())
+	}
+
+	events := event.NewBus()
+	events.SubscribeLowStock(event.WarnLowStock(logger))
+
+	catalogRepo := repository.NewCatalog(db)
+	catalogService := service.NewCatalog(catalogRepo)
+	catalogHandler := handler.NewCatalog(catalogService)
+
 	transactionRepo := repository.NewTransaction(db)
-	transactionService := service.NewTransaction(transactionRepo)
+	transactionService := service.NewTransaction(transactionRepo, repository.NewTxManager(db), events, s.cfg.History.InfoLimit, s.cfg.Returns.Window)
 	transactionHandler := handler.NewTransaction(transactionService)
 
+	fulfilmentService := service.NewFulfilment(transactionRepo)
+	fulfilmentHandler := handler.NewFulfilment(fulfilmentService)
+
+	paymentRequestService := service.NewPaymentRequests(transactionRepo, s.cfg.PaymentRequests.TTL)
+	paymentRequestHandler := handler.NewPaymentRequests(paymentRequestService)
+
+	kudosRepo := repository.NewKudos(db)
+	kudosService := service.NewKudos(kudosRepo)
+	kudosHandler := handler.NewKudos(kudosService)
+
 	app.Use(serverLogger.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: []string{},
 		AllowHeaders: []string{},
 	}))
 
-	mw := middleware.NewMDWManager(jwtService, logger)
+	mw := middleware.NewMDWManager(jwtService, authCache, tokenRepo, logger)
+
+	wellKnownGroup := app.Group("/.well-known")
+	routes.MapWellKnownRoutes(wellKnownGroup, keysHandler)
 
 	authGroup := app.Group("/api")
 	transactionGroup := app.Group("/api/transaction/")
 	transactionGroup.Use(mw.JWTMiddleware())
-	routes.MapAuthRoutes(authGroup, authHandler)
+	routes.MapAuthRoutes(authGroup, authHandler, mw.JWTMiddleware())
 	routes.MapTransactionRoutes(transactionGroup, transactionHandler)
+	routes.MapFulfilmentRoutes(transactionGroup, fulfilmentHandler)
+	routes.MapPaymentRequestRoutes(transactionGroup, paymentRequestHandler)
+
+	catalogGroup := app.Group("/api/items")
+	routes.MapCatalogRoutes(catalogGroup, catalogHandler)
+
+	kudosGroup := app.Group("/api/kudos", mw.JWTMiddleware())
+	routes.MapKudosRoutes(kudosGroup, kudosHandler)
+
+	adminGroup := app.Group("/api/admin", mw.JWTMiddleware(), mw.RequireRole(domain.RoleAdmin))
+	routes.MapAdminRoutes(adminGroup, roleHandler)
+	routes.MapAdminCatalogRoutes(adminGroup, catalogHandler)
+	routes.MapAdminFulfilmentRoutes(adminGroup, fulfilmentHandler)
 
 	return nil
 }
@@ -3,38 +3,81 @@ package jwt
 import (
 	"avito_test/internal/config"
 	"avito_test/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
-const tokenExpiration = 24 * time.Hour
+const tokenExpiration = 15 * time.Minute
 
 type Service struct {
-	config *config.Config
-	logger *logger.ApiLogger
+	config  *config.Config
+	logger  *logger.ApiLogger
+	keyring *Keyring
 }
 
 type Claims struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Roles     []string  `json:"roles"`
+	TokenID   string    `json:"jti"`
+	ExpiresAt time.Time `json:"exp"`
 }
 
+// NewJWTService signs tokens with HS256 and the shared secret.
 func NewJWTService(cfg *config.Config) *Service {
 	return &Service{
 		config: cfg,
 	}
 }
 
+// NewJWTServiceWithKeyring signs tokens with the active key of the keyring
+// and verifies them with any key still in its rotation window.
+func NewJWTServiceWithKeyring(cfg *config.Config, keyring *Keyring) *Service {
+	return &Service{
+		config:  cfg,
+		keyring: keyring,
+	}
+}
+
+// TTL returns the lifetime of issued access tokens.
+func (s *Service) TTL() time.Duration {
+	if s.config.Token.AccessTTL > 0 {
+		return s.config.Token.AccessTTL
+	}
+	return tokenExpiration
+}
+
 func (s *Service) GenerateJWT(claims Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	if claims.TokenID == "" {
+		claims.TokenID = uuid.NewString()
+	}
+
+	mapClaims := jwt.MapClaims{
 		"id":       claims.ID,
 		"username": claims.Username,
-		"exp":      time.Now().Add(tokenExpiration).Unix(),
-	})
+		"roles":    claims.Roles,
+		"jti":      claims.TokenID,
+		"exp":      time.Now().Add(s.TTL()).Unix(),
+	}
 
-	tokenString, err := token.SignedString([]byte(s.config.Auth.Secret))
+	var (
+		token *jwt.Token
+		key   interface{}
+	)
+	if s.keyring != nil {
+		active := s.keyring.Active()
+		token = jwt.NewWithClaims(active.Method, mapClaims)
+		token.Header["kid"] = active.ID
+		key = active.PrivateKey
+	} else {
+		token = jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+		key = []byte(s.config.Auth.Secret)
+	}
+
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", errors.WithMessage(err, "failed to sign JWT token")
 	}
@@ -43,9 +86,8 @@ func (s *Service) GenerateJWT(claims Claims) (string, error) {
 }
 
 func (s *Service) ParseToken(tokenString string) (Claims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.config.Auth.Secret), nil
-	})
+	parser := jwt.Parser{ValidMethods: s.validMethods()}
+	token, err := parser.Parse(tokenString, s.verificationKey)
 	if err != nil {
 		return Claims{}, errors.Wrap(err, "failed to parse token")
 	}
@@ -61,9 +103,84 @@ func (s *Service) ParseToken(tokenString string) (Claims, error) {
 	}
 
 	username, _ := claims["username"].(string)
+	tokenID, _ := claims["jti"].(string)
+
+	var roles []string
+	if rawRoles, ok := claims["roles"].([]interface{}); ok {
+		for _, rawRole := range rawRoles {
+			if role, ok := rawRole.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	var expiresAt time.Time
+	if exp, ok := claims["exp"].(float64); ok {
+		expiresAt = time.Unix(int64(exp), 0)
+	}
 
 	return Claims{
-		ID:       userID,
-		Username: username,
+		ID:        userID,
+		Username:  username,
+		Roles:     roles,
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
 	}, nil
 }
+
+// JWKS returns the public keys that may currently verify tokens.
+func (s *Service) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if s.keyring == nil {
+		return jwks
+	}
+
+	for _, key := range s.keyring.Keys() {
+		if jwk, ok := newJWK(key); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
+}
+
+func (s *Service) validMethods() []string {
+	if s.keyring == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return []string{jwt.SigningMethodRS256.Alg(), SigningMethodEd25519.Alg()}
+}
+
+func (s *Service) verificationKey(token *jwt.Token) (interface{}, error) {
+	if s.keyring == nil {
+		return []byte(s.config.Auth.Secret), nil
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("missing kid header")
+	}
+
+	key, ok := s.keyring.Lookup(kid)
+	if !ok {
+		return nil, errors.Errorf("unknown or retired key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.PublicKey, nil
+}
+
+// HasRole reports whether the claims carry at least one of the given roles.
+func (c Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
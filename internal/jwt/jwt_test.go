@@ -86,3 +86,36 @@ func TestParseToken_InvalidClaims(t *testing.T) {
 	_, err = jwtService.ParseToken(invalidClaimsTokenString)
 	assert.Error(t, err, "ParseToken should return an error for invalid claims")
 }
+
+func TestGenerateJWT_TokenID(t *testing.T) {
+	mockConfig := NewMockConfig()
+	jwtService := NewJWTService(mockConfig)
+
+	first, err := jwtService.GenerateJWT(Claims{ID: "123", Username: "testuser"})
+	assert.NoError(t, err, "GenerateJWT should not return an error")
+	second, err := jwtService.GenerateJWT(Claims{ID: "123", Username: "testuser"})
+	assert.NoError(t, err, "GenerateJWT should not return an error")
+
+	firstClaims, err := jwtService.ParseToken(first)
+	assert.NoError(t, err, "ParseToken should not return an error")
+	secondClaims, err := jwtService.ParseToken(second)
+	assert.NoError(t, err, "ParseToken should not return an error")
+
+	assert.NotEmpty(t, firstClaims.TokenID, "Token should carry a jti")
+	assert.NotEqual(t, firstClaims.TokenID, secondClaims.TokenID, "Every token should get its own jti")
+	assert.WithinDuration(t, time.Now().Add(tokenExpiration), firstClaims.ExpiresAt, time.Minute, "Token should be short-lived")
+}
+
+func TestGenerateJWT_Roles(t *testing.T) {
+	mockConfig := NewMockConfig()
+	jwtService := NewJWTService(mockConfig)
+
+	token, err := jwtService.GenerateJWT(Claims{ID: "123", Username: "testuser", Roles: []string{"user", "admin"}})
+	assert.NoError(t, err, "GenerateJWT should not return an error")
+
+	parsedClaims, err := jwtService.ParseToken(token)
+	assert.NoError(t, err, "ParseToken should not return an error")
+	assert.Equal(t, []string{"user", "admin"}, parsedClaims.Roles, "Parsed roles should match the original roles")
+	assert.True(t, parsedClaims.HasRole("auditor", "admin"), "HasRole should match any of the given roles")
+	assert.False(t, parsedClaims.HasRole("auditor"), "HasRole should not match a missing role")
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+// Key is a single signing key identified by its kid.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	// NotAfter is zero for the active key; retired keys verify until then.
+	NotAfter time.Time
+}
+
+// Keyring holds the active signing key and the retired keys that still
+// verify tokens during the rotation window.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// LoadKeyring reads every *.pem file in dir, using the file name without its
+// extension as the kid. The key named activeKID signs new tokens; when it is
+// empty the most recently modified private key is used. All other keys keep
+// verifying tokens for rotationWindow after the active key was created.
+func LoadKeyring(dir, activeKID string, rotationWindow time.Duration) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list key files")
+	}
+	sort.Strings(paths)
+
+	keyring := &Keyring{
+		keys: make(map[string]*Key, len(paths)),
+	}
+
+	var activeModTime time.Time
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to load key %s", path)
+		}
+		keyring.keys[key.ID] = key
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to stat key %s", path)
+		}
+
+		if key.PrivateKey == nil {
+			continue
+		}
+		if activeKID == key.ID || (activeKID == "" && !info.ModTime().Before(activeModTime)) {
+			keyring.active = key
+			activeModTime = info.ModTime()
+		}
+	}
+
+	if keyring.active == nil {
+		return nil, errors.Errorf("no private signing key found in %s", dir)
+	}
+
+	for _, key := range keyring.keys {
+		if key != keyring.active {
+			key.NotAfter = activeModTime.Add(rotationWindow)
+		}
+	}
+
+	return keyring, nil
+}
+
+// Active returns the key that signs new tokens.
+func (k *Keyring) Active() *Key {
+	return k.active
+}
+
+// Lookup returns the key with the given kid if it may still verify tokens.
+func (k *Keyring) Lookup(kid string) (*Key, bool) {
+	key, ok := k.keys[kid]
+	if !ok || key.expired(time.Now()) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Keys returns all keys that may still verify tokens, ordered by kid.
+func (k *Keyring) Keys() []*Key {
+	now := time.Now()
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (key *Key) expired(now time.Time) bool {
+	return !key.NotAfter.IsZero() && now.After(key.NotAfter)
+}
+
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read key file")
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &Key{
+		ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to parse key")
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = SigningMethodEd25519, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = SigningMethodEd25519, k
+	default:
+		return nil, errors.Errorf("unsupported key type %T", parsed)
+	}
+
+	return key, nil
+}
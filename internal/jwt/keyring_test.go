@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRSAKey(t *testing.T, dir, kid string, modTime time.Time) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	writePEM(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), modTime)
+}
+
+func writeEd25519Key(t *testing.T, dir, kid string, modTime time.Time) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	writePEM(t, dir, kid, "PRIVATE KEY", der, modTime)
+}
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte, modTime time.Time) {
+	t.Helper()
+
+	path := filepath.Join(dir, kid+".pem")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(t *testing.T, dir, kid string, modTime time.Time)
+		alg   string
+		kty   string
+	}{
+		{name: "RS256", write: writeRSAKey, alg: "RS256", kty: "RSA"},
+		{name: "EdDSA", write: writeEd25519Key, alg: "EdDSA", kty: "OKP"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.write(t, dir, "key-1", time.Now())
+
+			keyring, err := LoadKeyring(dir, "", time.Hour)
+			require.NoError(t, err)
+
+			jwtService := NewJWTServiceWithKeyring(NewMockConfig(), keyring)
+
+			token, err := jwtService.GenerateJWT(Claims{ID: "123", Username: "testuser"})
+			require.NoError(t, err)
+
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, parsed.Header["alg"], "Token should be signed with the key algorithm")
+			assert.Equal(t, "key-1", parsed.Header["kid"], "Token should carry the kid header")
+
+			claims, err := jwtService.ParseToken(token)
+			assert.NoError(t, err, "ParseToken should not return an error")
+			assert.Equal(t, "123", claims.ID)
+
+			jwks := jwtService.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "key-1", jwks.Keys[0].Kid)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "old", time.Now().Add(-2*time.Hour))
+
+	oldKeyring, err := LoadKeyring(dir, "", time.Hour)
+	require.NoError(t, err)
+	oldToken, err := NewJWTServiceWithKeyring(NewMockConfig(), oldKeyring).GenerateJWT(Claims{ID: "123"})
+	require.NoError(t, err)
+
+	writeEd25519Key(t, dir, "new", time.Now())
+
+	keyring, err := LoadKeyring(dir, "", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "new", keyring.Active().ID, "Most recent key should become active")
+
+	jwtService := NewJWTServiceWithKeyring(NewMockConfig(), keyring)
+	_, err = jwtService.ParseToken(oldToken)
+	assert.NoError(t, err, "Token signed by the previous key should verify during the rotation window")
+	assert.Len(t, jwtService.JWKS().Keys, 2, "Previous key should stay published during the rotation window")
+
+	expiredKeyring, err := LoadKeyring(dir, "", -time.Minute)
+	require.NoError(t, err)
+
+	expiredService := NewJWTServiceWithKeyring(NewMockConfig(), expiredKeyring)
+	_, err = expiredService.ParseToken(oldToken)
+	assert.Error(t, err, "Token signed by a retired key should fail after the rotation window")
+	assert.Len(t, expiredService.JWKS().Keys, 1, "Retired key should not be published after the rotation window")
+}
+
+func TestKeyring_RejectsUnexpectedAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "key-1", time.Now())
+
+	keyring, err := LoadKeyring(dir, "", time.Hour)
+	require.NoError(t, err)
+	jwtService := NewJWTServiceWithKeyring(NewMockConfig(), keyring)
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":  "123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	hmacToken.Header["kid"] = "key-1"
+	tokenString, err := hmacToken.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = jwtService.ParseToken(tokenString)
+	assert.Error(t, err, "ParseToken should reject HS256 tokens when asymmetric keys are configured")
+
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"id": "123"})
+	tokenString, err = noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = jwtService.ParseToken(tokenString)
+	assert.Error(t, err, "ParseToken should reject unsigned tokens")
+}
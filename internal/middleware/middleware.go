@@ -1,21 +1,34 @@
 package middleware
 
 import (
+	"avito_test/internal/entity"
 	"avito_test/internal/jwt"
+	"avito_test/internal/repository"
 	"avito_test/pkg/logger"
 	"github.com/gofiber/fiber/v3"
+	"golang.org/x/net/context"
 	"strings"
 )
 
+type RevokedTokenRepository interface {
+	GetRevokedAccessToken(ctx context.Context, jti string) (*entity.RevokedToken, error)
+}
+
+// MDWManager checks revocations in the local cache first and falls back to the
+// database, so a logout handled by another instance is seen here as well.
 type MDWManager struct {
-	jwt    *jwt.Service
-	logger *logger.ApiLogger
+	jwt     *jwt.Service
+	revoked repository.AuthCache
+	tokens  RevokedTokenRepository
+	logger  *logger.ApiLogger
 }
 
-func NewMDWManager(jwt *jwt.Service, logger *logger.ApiLogger) *MDWManager {
+func NewMDWManager(jwt *jwt.Service, revoked repository.AuthCache, tokens RevokedTokenRepository, logger *logger.ApiLogger) *MDWManager {
 	return &MDWManager{
-		jwt:    jwt,
-		logger: logger,
+		jwt:     jwt,
+		revoked: revoked,
+		tokens:  tokens,
+		logger:  logger,
 	}
 }
 
@@ -43,8 +56,63 @@ func (mw *MDWManager) JWTMiddleware() fiber.Handler {
 			})
 		}
 
+		revoked, err := mw.isRevoked(ctx.Context(), claims.TokenID)
+		if err != nil {
+			mw.logger.Errorf("error checking token revocation: %v", err)
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "internal server error",
+			})
+		}
+		if revoked {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "token has been revoked",
+			})
+		}
+
 		ctx.Locals("id", claims.ID)
+		ctx.Locals("claims", claims)
+
+		return ctx.Next()
+	}
+}
+
+// RequireRole lets the request through only when the token carries at least
+// one of the given roles. It must run after JWTMiddleware.
+func (mw *MDWManager) RequireRole(roles ...string) fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		claims, ok := ctx.Locals("claims").(jwt.Claims)
+		if !ok {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing or invalid Authorization header",
+			})
+		}
+
+		if !claims.HasRole(roles...) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "insufficient permissions",
+			})
+		}
 
 		return ctx.Next()
 	}
 }
+
+func (mw *MDWManager) isRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	if _, revoked := mw.revoked.Get(jti); revoked {
+		return true, nil
+	}
+
+	token, err := mw.tokens.GetRevokedAccessToken(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		return false, nil
+	}
+
+	mw.revoked.Set(token.Jti, token.ExpiresAt)
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"avito_test/internal/config"
+	"avito_test/internal/entity"
+	"avito_test/internal/jwt"
+	"avito_test/internal/repository"
+	"avito_test/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// revokedTokens stands in for revoked_access_tokens, which other instances
+// write to as well.
+type revokedTokens struct {
+	expiresAt map[string]time.Time
+	err       error
+}
+
+func (r *revokedTokens) GetRevokedAccessToken(_ context.Context, jti string) (*entity.RevokedToken, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	expiresAt, ok := r.expiresAt[jti]
+	if !ok {
+		return nil, nil
+	}
+	return &entity.RevokedToken{Jti: jti, ExpiresAt: expiresAt}, nil
+}
+
+func newTestApp(t *testing.T) (*fiber.App, *jwt.Service, *repository.MemoryAuthCache, *revokedTokens) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Auth.Secret = "secret"
+
+	apiLogger := logger.NewApiLogger(cfg)
+	require.NoError(t, apiLogger.InitLogger())
+
+	jwtService := jwt.NewJWTService(cfg)
+	cache := repository.NewMemoryAuthCache()
+	tokens := &revokedTokens{expiresAt: make(map[string]time.Time)}
+	mw := NewMDWManager(jwtService, cache, tokens, apiLogger)
+
+	app := fiber.New()
+	app.Get("/admin", func(ctx fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	}, mw.JWTMiddleware(), mw.RequireRole("admin"))
+
+	return app, jwtService, cache, tokens
+}
+
+func TestRequireRole(t *testing.T) {
+	app, jwtService, _, _ := newTestApp(t)
+
+	tests := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+	}{
+		{name: "Admin", roles: []string{"user", "admin"}, expectedStatus: fiber.StatusOK},
+		{name: "Regular User", roles: []string{"user"}, expectedStatus: fiber.StatusForbidden},
+		{name: "No Roles", roles: nil, expectedStatus: fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwtService.GenerateJWT(jwt.Claims{ID: "123", Username: "testuser", Roles: tt.roles})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestJWTMiddleware_RevokedToken(t *testing.T) {
+	app, jwtService, cache, _ := newTestApp(t)
+
+	token, err := jwtService.GenerateJWT(jwt.Claims{ID: "123", Username: "testuser", Roles: []string{"admin"}})
+	require.NoError(t, err)
+
+	claims, err := jwtService.ParseToken(token)
+	require.NoError(t, err)
+	cache.Set(claims.TokenID, time.Now().Add(time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestJWTMiddleware_RevokedElsewhere(t *testing.T) {
+	app, jwtService, cache, tokens := newTestApp(t)
+
+	token, err := jwtService.GenerateJWT(jwt.Claims{ID: "123", Username: "testuser", Roles: []string{"admin"}})
+	require.NoError(t, err)
+
+	claims, err := jwtService.ParseToken(token)
+	require.NoError(t, err)
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	require.Equal(t, fiber.StatusOK, send())
+
+	// Another instance handled the logout: only the database knows about it.
+	tokens.expiresAt[claims.TokenID] = time.Now().Add(time.Hour)
+	require.Equal(t, fiber.StatusUnauthorized, send())
+
+	_, cached := cache.Get(claims.TokenID)
+	require.True(t, cached, "Revocation found in the database should be cached")
+
+	tokens.err = errors.New("db error")
+	require.Equal(t, fiber.StatusUnauthorized, send(), "Cached revocation should not need the database")
+}
+
+func TestJWTMiddleware_RevocationCheckFails(t *testing.T) {
+	app, jwtService, _, tokens := newTestApp(t)
+	tokens.err = errors.New("db error")
+
+	token, err := jwtService.GenerateJWT(jwt.Claims{ID: "123", Username: "testuser", Roles: []string{"admin"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
@@ -1,19 +1,18 @@
 package repository
 
 import (
+	"avito_test/internal/domain"
 	"avito_test/internal/entity"
 	"avito_test/pkg/storage/postgres"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"time"
 )
 
-type AuthCache interface {
-	Get(username string) (string, bool)
-	Set(user string)
-	Delete(username string)
-}
+const uniqueViolationCode = "23505"
 
 type Auth struct {
 	db postgres.Postgres
@@ -25,11 +24,32 @@ func NewAuth(db postgres.Postgres) Auth {
 	}
 }
 
-func (a Auth) Auth(ctx context.Context, auth entity.Auth) (*entity.Auth, error) {
+func (a Auth) GetByUsername(ctx context.Context, username string) (*entity.Auth, error) {
+	var auth entity.Auth
+
+	query := `SELECT a.id, a.username, a.password, 
+				COALESCE(array_agg(ur.role ORDER BY ur.role) FILTER (WHERE ur.role IS NOT NULL), '{}') AS roles 
+			  FROM auth a 
+			  LEFT JOIN user_roles ur ON ur.user_id = a.id 
+			  WHERE a.username = $1 
+			  GROUP BY a.id`
+	err := postgres.Conn(ctx, a.db).Get(ctx, &auth, query, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get auth by username")
+	}
+
+	return &auth, nil
+}
+
+func (a Auth) Create(ctx context.Context, auth entity.Auth) (*entity.Auth, error) {
 	auth = entity.Auth{
 		Id:       uuid.New(),
 		Username: auth.Username,
 		Password: auth.Password,
+		Roles:    []string{domain.RoleUser},
 	}
 
 	user := entity.User{
@@ -45,6 +65,9 @@ func (a Auth) Auth(ctx context.Context, auth entity.Auth) (*entity.Auth, error)
 			VALUES ($1, $2, $3)
 		`
 		_, err := tx.Exec(ctx, queryAuth, auth.Id, auth.Username, auth.Password)
+		if isUniqueViolation(err) {
+			return domain.ErrUserAlreadyExists
+		}
 		if err != nil {
 			return errors.Wrap(err, "failed to create auth in database")
 		}
@@ -54,10 +77,31 @@ func (a Auth) Auth(ctx context.Context, auth entity.Auth) (*entity.Auth, error)
 			VALUES ($1, $2, $3, $4)
 		`
 		_, err = tx.Exec(ctx, queryUser, user.Id, user.Username, user.Coin, user.CreatedAt)
+		if isUniqueViolation(err) {
+			return domain.ErrUserAlreadyExists
+		}
 		if err != nil {
 			return errors.Wrap(err, "failed to create user in database")
 		}
 
+		queryRole := `INSERT INTO user_roles (user_id, role) VALUES ($1, $2)`
+		_, err = tx.Exec(ctx, queryRole, user.Id, domain.RoleUser)
+		if err != nil {
+			return errors.Wrap(err, "failed to assign default role")
+		}
+
+		if err = createLedgerAccount(ctx, tx, user.Id); err != nil {
+			return err
+		}
+
+		_, err = postJournalEntry(ctx, tx, entity.JournalGrant, user.Id.String(),
+			entity.Posting{AccountId: entity.IssuanceAccountID, Amount: -user.Coin},
+			entity.Posting{AccountId: user.Id, Amount: user.Coin},
+		)
+		if err != nil {
+			return errors.WithMessage(err, "failed to grant starting coins")
+		}
+
 		return nil
 	})
 
@@ -67,3 +111,74 @@ func (a Auth) Auth(ctx context.Context, auth entity.Auth) (*entity.Auth, error)
 
 	return &auth, nil
 }
+
+func (a Auth) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE auth SET password = $1 WHERE id = $2`
+	_, err := postgres.Conn(ctx, a.db).Exec(ctx, query, passwordHash, id)
+	if err != nil {
+		return errors.WithMessage(err, "failed to update password hash")
+	}
+
+	return nil
+}
+
+func (a Auth) GetRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var roles []string
+
+	query := `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+	err := postgres.Conn(ctx, a.db).Select(ctx, &roles, query, userID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get user roles")
+	}
+
+	return roles, nil
+}
+
+func (a Auth) AddRole(ctx context.Context, username, role string) ([]string, error) {
+	return a.changeRole(ctx, username, `
+		INSERT INTO user_roles (user_id, role) 
+		VALUES ($1, $2) 
+		ON CONFLICT (user_id, role) DO NOTHING`, role)
+}
+
+func (a Auth) RemoveRole(ctx context.Context, username, role string) ([]string, error) {
+	return a.changeRole(ctx, username, `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, role)
+}
+
+func (a Auth) changeRole(ctx context.Context, username, query, role string) ([]string, error) {
+	var roles []string
+
+	err := postgres.ExecTx(ctx, a.db, func(tx postgres.Tx) error {
+		var userID uuid.UUID
+		err := tx.Get(ctx, &userID, `SELECT id FROM users WHERE username = $1`, username)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrUserNotFound
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to get user by username")
+		}
+
+		_, err = tx.Exec(ctx, query, userID, role)
+		if err != nil {
+			return errors.WithMessage(err, "failed to change user role")
+		}
+
+		err = tx.Select(ctx, &roles, `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`, userID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get user roles")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return roles, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+const authCacheCleanupInterval = time.Minute
+
+// AuthCache keeps revoked access token IDs until the tokens themselves expire.
+type AuthCache interface {
+	Get(jti string) (time.Time, bool)
+	Set(jti string, expiresAt time.Time)
+	Delete(jti string)
+}
+
+type MemoryAuthCache struct {
+	mu          sync.RWMutex
+	items       map[string]time.Time
+	nextCleanup time.Time
+}
+
+func NewMemoryAuthCache() *MemoryAuthCache {
+	return &MemoryAuthCache{
+		items:       make(map[string]time.Time),
+		nextCleanup: time.Now().Add(authCacheCleanupInterval),
+	}
+}
+
+func (c *MemoryAuthCache) Get(jti string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	expiresAt, ok := c.items[jti]
+	if !ok || time.Now().After(expiresAt) {
+		return time.Time{}, false
+	}
+
+	return expiresAt, true
+}
+
+func (c *MemoryAuthCache) Set(jti string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.After(c.nextCleanup) {
+		for key, exp := range c.items {
+			if now.After(exp) {
+				delete(c.items, key)
+			}
+		}
+		c.nextCleanup = now.Add(authCacheCleanupInterval)
+	}
+
+	c.items[jti] = expiresAt
+}
+
+func (c *MemoryAuthCache) Delete(jti string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, jti)
+}
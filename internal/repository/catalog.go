@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type Catalog struct {
+	db postgres.Postgres
+}
+
+func NewCatalog(db postgres.Postgres) Catalog {
+	return Catalog{
+		db: db,
+	}
+}
+
+// ListItems returns the items that are still on sale with their variants;
+// retired items are left out.
+func (c Catalog) ListItems(ctx context.Context) ([]entity.CatalogItem, error) {
+	var items []entity.CatalogItem
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		items = nil
+
+		query := `SELECT id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at 
+				  FROM items 
+				  WHERE retired_at IS NULL 
+				  ORDER BY category, type`
+		err := tx.Select(ctx, &items, query)
+		if err != nil {
+			return errors.WithMessage(err, "failed to list catalog items")
+		}
+
+		return loadItemVariants(ctx, tx, items)
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return items, nil
+}
+
+func (c Catalog) GetItem(ctx context.Context, itemType string) (*entity.CatalogItem, error) {
+	var items []entity.CatalogItem
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		query := `SELECT id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at 
+				  FROM items 
+				  WHERE type = $1`
+		err := tx.Select(ctx, &items, query, itemType)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get catalog item")
+		}
+		if len(items) == 0 {
+			return domain.ErrItemNotFound
+		}
+
+		return loadItemVariants(ctx, tx, items)
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &items[0], nil
+}
+
+func (c Catalog) CreateItem(ctx context.Context, item entity.CatalogItem, changedBy uuid.UUID) (*entity.CatalogItem, error) {
+	item.Id = uuid.New()
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		query := `INSERT INTO items (id, type, price, description, category, available, stock, low_stock_threshold) 
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+				  RETURNING id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at`
+		err := tx.Get(ctx, &item, query, item.Id, item.Type, item.Price, item.Description, item.Category, item.Available,
+			item.Stock, item.LowStockThreshold)
+		if isUniqueViolation(err) {
+			return domain.ErrItemAlreadyExists
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to create item")
+		}
+
+		return recordItemChange(ctx, tx, item, entity.ItemChangeCreate, changedBy)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &item, nil
+}
+
+// UpdateItem applies the non-nil fields of update. Retired items cannot be changed.
+func (c Catalog) UpdateItem(ctx context.Context, itemType string, update entity.ItemUpdate, changedBy uuid.UUID) (*entity.CatalogItem, error) {
+	var item entity.CatalogItem
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		err := lockItem(ctx, tx, itemType, &item)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE items 
+				  SET price = COALESCE($2, price), 
+					  description = COALESCE($3, description), 
+					  category = COALESCE($4, category), 
+					  available = COALESCE($5, available), 
+					  low_stock_threshold = COALESCE($6, low_stock_threshold), 
+					  updated_at = CURRENT_TIMESTAMP 
+				  WHERE id = $1 
+				  RETURNING id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at`
+		err = tx.Get(ctx, &item, query, item.Id, update.Price, update.Description, update.Category, update.Available,
+			update.LowStockThreshold)
+		if err != nil {
+			return errors.WithMessage(err, "failed to update item")
+		}
+
+		err = recordItemChange(ctx, tx, item, entity.ItemChangeUpdate, changedBy)
+		if err != nil {
+			return err
+		}
+
+		return loadVariantsOf(ctx, tx, &item)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &item, nil
+}
+
+// RetireItem takes the item off sale for good. Owned copies stay in inventories.
+func (c Catalog) RetireItem(ctx context.Context, itemType string, changedBy uuid.UUID) (*entity.CatalogItem, error) {
+	var item entity.CatalogItem
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		err := lockItem(ctx, tx, itemType, &item)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE items 
+				  SET retired_at = CURRENT_TIMESTAMP, available = FALSE, updated_at = CURRENT_TIMESTAMP 
+				  WHERE id = $1 
+				  RETURNING id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at`
+		err = tx.Get(ctx, &item, query, item.Id)
+		if err != nil {
+			return errors.WithMessage(err, "failed to retire item")
+		}
+
+		err = recordItemChange(ctx, tx, item, entity.ItemChangeRetire, changedBy)
+		if err != nil {
+			return err
+		}
+
+		return loadVariantsOf(ctx, tx, &item)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &item, nil
+}
+
+// CreateVariant adds a variant to the item and returns the item with all its
+// variants. Once an item has variants, buyers have to pick one.
+func (c Catalog) CreateVariant(ctx context.Context, itemType string, variant entity.ItemVariant, changedBy uuid.UUID) (*entity.CatalogItem, error) {
+	var item entity.CatalogItem
+	variant.Id = uuid.New()
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		err := lockItem(ctx, tx, itemType, &item)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO item_variants (id, item_id, sku, size, color, price, stock) 
+				  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		_, err = tx.Exec(ctx, query, variant.Id, item.Id, variant.Sku, variant.Size, variant.Color, variant.Price, variant.Stock)
+		if isUniqueViolation(err) {
+			return domain.ErrVariantAlreadyExists
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to create variant")
+		}
+
+		err = recordVariantChange(ctx, tx, item, variant, entity.ItemChangeCreate, changedBy)
+		if err != nil {
+			return err
+		}
+
+		return loadVariantsOf(ctx, tx, &item)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &item, nil
+}
+
+// RestockItem adds quantity to the stock of the item, or of one of its variants
+// when sku is set. Anything sold in unlimited supply becomes limited to quantity.
+func (c Catalog) RestockItem(ctx context.Context, itemType string, sku string, quantity int, changedBy uuid.UUID) (*entity.CatalogItem, error) {
+	var item entity.CatalogItem
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		err := lockItem(ctx, tx, itemType, &item)
+		if err != nil {
+			return err
+		}
+
+		if sku != "" {
+			var variant entity.ItemVariant
+			query := `UPDATE item_variants 
+					  SET stock = COALESCE(stock, 0) + $3 
+					  WHERE item_id = $1 AND sku = $2 
+					  RETURNING id, item_id, sku, size, color, price, stock, created_at`
+			err = tx.Get(ctx, &variant, query, item.Id, sku, quantity)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return domain.ErrVariantNotFound
+			}
+			if err != nil {
+				return errors.WithMessage(err, "failed to restock variant")
+			}
+
+			err = recordVariantChange(ctx, tx, item, variant, entity.ItemChangeRestock, changedBy)
+			if err != nil {
+				return err
+			}
+
+			return loadVariantsOf(ctx, tx, &item)
+		}
+
+		query := `UPDATE items 
+				  SET stock = COALESCE(stock, 0) + $2, updated_at = CURRENT_TIMESTAMP 
+				  WHERE id = $1 
+				  RETURNING id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at`
+		err = tx.Get(ctx, &item, query, item.Id, quantity)
+		if err != nil {
+			return errors.WithMessage(err, "failed to restock item")
+		}
+
+		err = recordItemChange(ctx, tx, item, entity.ItemChangeRestock, changedBy)
+		if err != nil {
+			return err
+		}
+
+		return loadVariantsOf(ctx, tx, &item)
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &item, nil
+}
+
+// GetPriceHistory lists every recorded change of the item, newest first.
+func (c Catalog) GetPriceHistory(ctx context.Context, itemType string) ([]entity.PriceChange, error) {
+	var changes []entity.PriceChange
+
+	err := postgres.ExecTx(ctx, c.db, func(tx postgres.Tx) error {
+		var itemID uuid.UUID
+		err := tx.Get(ctx, &itemID, `SELECT id FROM items WHERE type = $1`, itemType)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrItemNotFound
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to get item")
+		}
+
+		query := `SELECT change, COALESCE(variant, '') AS variant, price, description, category, available, 
+				  stock, low_stock_threshold, changed_by, changed_at 
+				  FROM item_price_history 
+				  WHERE item_id = $1 
+				  ORDER BY changed_at DESC, id DESC`
+		err = tx.Select(ctx, &changes, query, itemID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get price history")
+		}
+
+		return nil
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return changes, nil
+}
+
+func lockItem(ctx context.Context, tx postgres.Tx, itemType string, item *entity.CatalogItem) error {
+	query := `SELECT id, type, price, description, category, available, stock, low_stock_threshold, retired_at, updated_at 
+			  FROM items 
+			  WHERE type = $1 
+			  FOR UPDATE`
+	err := tx.Get(ctx, item, query, itemType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrItemNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "failed to lock item")
+	}
+	if item.RetiredAt != nil {
+		return domain.ErrItemRetired
+	}
+
+	return nil
+}
+
+func recordItemChange(ctx context.Context, tx postgres.Tx, item entity.CatalogItem, change string, changedBy uuid.UUID) error {
+	query := `INSERT INTO item_price_history 
+			  (item_id, change, price, description, category, available, stock, low_stock_threshold, changed_by) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := tx.Exec(ctx, query, item.Id, change, item.Price, item.Description, item.Category, item.Available,
+		item.Stock, item.LowStockThreshold, changedBy)
+	if err != nil {
+		return errors.WithMessage(err, "failed to record price history")
+	}
+
+	return nil
+}
+
+// recordVariantChange snapshots the item with the variant's effective price and stock.
+func recordVariantChange(
+	ctx context.Context,
+	tx postgres.Tx,
+	item entity.CatalogItem,
+	variant entity.ItemVariant,
+	change string,
+	changedBy uuid.UUID,
+) error {
+	price := item.Price
+	if variant.Price != nil {
+		price = *variant.Price
+	}
+
+	query := `INSERT INTO item_price_history 
+			  (item_id, variant, change, price, description, category, available, stock, low_stock_threshold, changed_by) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := tx.Exec(ctx, query, item.Id, variant.Sku, change, price, item.Description, item.Category, item.Available,
+		variant.Stock, item.LowStockThreshold, changedBy)
+	if err != nil {
+		return errors.WithMessage(err, "failed to record price history")
+	}
+
+	return nil
+}
+
+func loadVariantsOf(ctx context.Context, tx postgres.Tx, item *entity.CatalogItem) error {
+	items := []entity.CatalogItem{*item}
+	if err := loadItemVariants(ctx, tx, items); err != nil {
+		return err
+	}
+
+	item.Variants = items[0].Variants
+	return nil
+}
+
+func loadItemVariants(ctx context.Context, tx postgres.Tx, items []entity.CatalogItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(items))
+	byID := make(map[uuid.UUID]*entity.CatalogItem, len(items))
+	for i := range items {
+		ids = append(ids, items[i].Id)
+		byID[items[i].Id] = &items[i]
+		items[i].Variants = nil
+	}
+
+	var variants []entity.ItemVariant
+	query := `SELECT id, item_id, sku, size, color, price, stock, created_at 
+			  FROM item_variants 
+			  WHERE item_id = ANY($1) 
+			  ORDER BY created_at, sku`
+	err := tx.Select(ctx, &variants, query, ids)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get item variants")
+	}
+
+	for _, variant := range variants {
+		item := byID[variant.ItemId]
+		item.Variants = append(item.Variants, variant)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog_Items(t *testing.T) {
+	db := newTestDB(t)
+	catalog := NewCatalog(db)
+	ctx := context.Background()
+
+	items, err := catalog.ListItems(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, items)
+
+	item, err := catalog.GetItem(ctx, "cup")
+	require.NoError(t, err)
+	require.EqualValues(t, 20, item.Price)
+	require.Equal(t, "drinkware", item.Category)
+
+	_, err = catalog.GetItem(ctx, "no-such-item")
+	require.ErrorIs(t, err, domain.ErrItemNotFound)
+}
+
+func TestCatalog_AdminLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	catalog := NewCatalog(db)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	admin := createTestUser(t, db, 0)
+	buyer := createTestUser(t, db, 1000)
+
+	itemType := "test-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		_, _ = db.Exec(ctx, `DELETE FROM items WHERE type = $1`, itemType)
+	})
+
+	created, err := catalog.CreateItem(ctx, entity.CatalogItem{
+		Type: itemType, Price: 40, Category: "other", Available: true,
+	}, admin.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 40, created.Price)
+
+	_, err = catalog.CreateItem(ctx, entity.CatalogItem{Type: itemType, Price: 1, Category: "other"}, admin.Id)
+	require.ErrorIs(t, err, domain.ErrItemAlreadyExists)
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, itemType, "", nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 960, userCoins(t, db, buyer.Id))
+
+	price := int64(70)
+	updated, err := catalog.UpdateItem(ctx, itemType, entity.ItemUpdate{Price: &price}, admin.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 70, updated.Price)
+	require.Equal(t, "other", updated.Category)
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, itemType, "", nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 890, userCoins(t, db, buyer.Id))
+
+	retired, err := catalog.RetireItem(ctx, itemType, admin.Id)
+	require.NoError(t, err)
+	require.NotNil(t, retired.RetiredAt)
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, itemType, "", nil)
+	require.ErrorIs(t, err, domain.ErrItemRetired)
+	require.EqualValues(t, 890, userCoins(t, db, buyer.Id))
+
+	_, err = catalog.UpdateItem(ctx, itemType, entity.ItemUpdate{Price: &price}, admin.Id)
+	require.ErrorIs(t, err, domain.ErrItemRetired)
+
+	items, err := catalog.ListItems(ctx)
+	require.NoError(t, err)
+	for _, item := range items {
+		require.NotEqual(t, itemType, item.Type)
+	}
+
+	info, err := repo.GetInfo(ctx, buyer.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, info.Inventory, 1)
+	require.EqualValues(t, 2, info.Inventory[0].Quantity)
+
+	history, err := catalog.GetPriceHistory(ctx, itemType)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, entity.ItemChangeRetire, history[0].Change)
+	require.Equal(t, entity.ItemChangeUpdate, history[1].Change)
+	require.EqualValues(t, 70, history[1].Price)
+	require.Equal(t, entity.ItemChangeCreate, history[2].Change)
+	require.Equal(t, admin.Id, *history[2].ChangedBy)
+}
+
+func TestCatalog_LimitedStock_Concurrent(t *testing.T) {
+	db := newTestDB(t)
+	catalog := NewCatalog(db)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	admin := createTestUser(t, db, 0)
+
+	itemType := "test-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		_, _ = db.Exec(ctx, `DELETE FROM items WHERE type = $1`, itemType)
+	})
+
+	stock, threshold := 3, 1
+	_, err := catalog.CreateItem(ctx, entity.CatalogItem{
+		Type: itemType, Price: 10, Category: "other", Available: true, Stock: &stock, LowStockThreshold: &threshold,
+	}, admin.Id)
+	require.NoError(t, err)
+
+	const buyers = 5
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		bought     int
+		outOfStock int
+		lowStock   []entity.StockLevel
+	)
+	for range buyers {
+		buyer := createTestUser(t, db, 100)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, _, err := repo.BuyItem(ctx, buyer.Id, itemType, "", nil)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, domain.ErrOutOfStock):
+				outOfStock++
+			case err != nil:
+				t.Errorf("buy failed: %v", err)
+			default:
+				bought++
+				lowStock = append(lowStock, order.LowStock...)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, stock, bought)
+	require.Equal(t, buyers-stock, outOfStock)
+	require.Equal(t, []entity.StockLevel{{ItemType: itemType, Stock: 1, Threshold: 1}}, lowStock)
+
+	item, err := catalog.GetItem(ctx, itemType)
+	require.NoError(t, err)
+	require.Equal(t, 0, *item.Stock)
+
+	restocked, err := catalog.RestockItem(ctx, itemType, "", 4, admin.Id)
+	require.NoError(t, err)
+	require.Equal(t, 4, *restocked.Stock)
+
+	history, err := catalog.GetPriceHistory(ctx, itemType)
+	require.NoError(t, err)
+	require.Equal(t, entity.ItemChangeRestock, history[0].Change)
+	require.Equal(t, 4, *history[0].Stock)
+}
+
+func TestCatalog_Variants(t *testing.T) {
+	db := newTestDB(t)
+	catalog := NewCatalog(db)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	admin := createTestUser(t, db, 0)
+	buyer := createTestUser(t, db, 1000)
+
+	itemType := "test-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		_, _ = db.Exec(ctx, `DELETE FROM items WHERE type = $1`, itemType)
+	})
+
+	_, err := catalog.CreateItem(ctx, entity.CatalogItem{Type: itemType, Price: 100, Category: "apparel", Available: true}, admin.Id)
+	require.NoError(t, err)
+
+	price, stock := int64(150), 2
+	_, err = catalog.CreateVariant(ctx, itemType, entity.ItemVariant{Sku: itemType + "-M", Size: "M", Price: &price, Stock: &stock}, admin.Id)
+	require.NoError(t, err)
+	item, err := catalog.CreateVariant(ctx, itemType, entity.ItemVariant{Sku: itemType + "-L", Size: "L"}, admin.Id)
+	require.NoError(t, err)
+	require.Len(t, item.Variants, 2)
+
+	_, err = catalog.CreateVariant(ctx, itemType, entity.ItemVariant{Sku: itemType + "-L"}, admin.Id)
+	require.ErrorIs(t, err, domain.ErrVariantAlreadyExists)
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, itemType, "", nil)
+	require.ErrorIs(t, err, domain.ErrVariantRequired)
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, "cup", itemType+"-M", nil)
+	require.ErrorIs(t, err, domain.ErrVariantNotFound)
+
+	order, _, err := repo.BuyItem(ctx, buyer.Id, itemType, itemType+"-M", nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 150, order.Total)
+
+	order, _, err = repo.Checkout(ctx, buyer.Id, []entity.OrderLine{
+		{ItemType: itemType, Variant: itemType + "-L", Quantity: 2},
+		{ItemType: itemType, Variant: itemType + "-M", Quantity: 1},
+		{ItemType: "cup", Quantity: 1},
+	}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 2*100+150+20, order.Total)
+	require.EqualValues(t, 1000-150-370, userCoins(t, db, buyer.Id))
+
+	_, _, err = repo.BuyItem(ctx, buyer.Id, itemType, itemType+"-M", nil)
+	require.ErrorIs(t, err, domain.ErrOutOfStock)
+
+	info, err := repo.GetInfo(ctx, buyer.Id, 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []entity.Item{
+		{Type: itemType, Variant: itemType + "-M", Quantity: 2},
+		{Type: itemType, Variant: itemType + "-L", Quantity: 2},
+		{Type: "cup", Quantity: 1},
+	}, info.Inventory)
+	require.Equal(t, itemType+"-L", info.RecentPurchases[0].Lines[0].Variant)
+
+	item, err = catalog.RestockItem(ctx, itemType, itemType+"-M", 3, admin.Id)
+	require.NoError(t, err)
+	for _, variant := range item.Variants {
+		if variant.Sku == itemType+"-M" {
+			require.Equal(t, 3, *variant.Stock)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strings"
+)
+
+// ListOrders returns one page of the orders matching the filter, newest first,
+// fetching up to filter.Limit+1 rows so the caller can tell whether another page exists.
+func (t Transaction) ListOrders(ctx context.Context, filter entity.OrderFilter) ([]entity.Order, error) {
+	var orders []entity.Order
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		orders = nil
+
+		var args []any
+		arg := func(value any) string {
+			args = append(args, value)
+			return fmt.Sprintf("$%d", len(args))
+		}
+
+		conditions := []string{"o.status = ANY(" + arg(filter.Statuses) + ")"}
+		if filter.UserId != nil {
+			conditions = append(conditions, "o.user_id = "+arg(*filter.UserId))
+		}
+		if filter.After != nil {
+			conditions = append(conditions, fmt.Sprintf("(o.created_at, o.id) < (%s, %s)", arg(filter.After.CreatedAt), arg(filter.After.Id)))
+		}
+
+		query := `SELECT o.id, o.user_id, u.username, o.total, o.status, o.created_at 
+				  FROM orders o 
+				  JOIN users u ON u.id = o.user_id 
+				  WHERE ` + strings.Join(conditions, " AND ") + ` 
+				  ORDER BY o.created_at DESC, o.id DESC 
+				  LIMIT ` + arg(filter.Limit+1)
+		err := tx.Select(ctx, &orders, query, args...)
+		if err != nil {
+			return errors.WithMessage(err, "failed to list orders")
+		}
+
+		return loadOrderLines(ctx, tx, orders)
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return orders, nil
+}
+
+func (t Transaction) GetOrderStatus(ctx context.Context, orderID int64) (string, error) {
+	var statuses []string
+	query := `SELECT status FROM orders WHERE id = $1`
+	err := postgres.Conn(ctx, t.db).Select(ctx, &statuses, query, orderID)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to get order status")
+	}
+	if len(statuses) == 0 {
+		return "", domain.ErrOrderNotFound
+	}
+
+	return statuses[0], nil
+}
+
+// SetOrderStatus moves the order from one status to another. It returns
+// ErrInvalidOrderTransition when the order is no longer in the from status.
+func (t Transaction) SetOrderStatus(ctx context.Context, orderID int64, from, to string, changedBy uuid.UUID) (*entity.Order, error) {
+	var order *entity.Order
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		err := changeOrderStatus(ctx, tx, orderID, from, to, changedBy)
+		if err != nil {
+			return err
+		}
+
+		order, err = getOrder(ctx, tx, orderID)
+		return err
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return order, nil
+}
+
+// CancelOrder cancels an order that is still in the from status and refunds
+// whatever has not been returned yet: the items leave the inventory of whoever
+// holds them, the buyer or a gift's recipient, and their coins go back to the buyer.
+func (t Transaction) CancelOrder(ctx context.Context, orderID int64, from string, changedBy uuid.UUID) (*entity.Order, error) {
+	var order *entity.Order
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		err := changeOrderStatus(ctx, tx, orderID, from, entity.OrderCancelled, changedBy)
+		if err != nil {
+			return err
+		}
+
+		var buyerID uuid.UUID
+		query := `SELECT user_id FROM orders WHERE id = $1`
+		err = tx.Get(ctx, &buyerID, query, orderID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get order buyer")
+		}
+
+		var recipients []uuid.UUID
+		query = `SELECT to_user FROM gifts WHERE order_id = $1`
+		err = tx.Select(ctx, &recipients, query, orderID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get gift recipient")
+		}
+		holderID := buyerID
+		if len(recipients) > 0 {
+			holderID = recipients[0]
+		}
+
+		// Lines are locked in type order, so stock is put back in the order lockOrderItems locks it.
+		var lines []refundableLine
+		query = `SELECT id, item_type, COALESCE(variant, '') AS variant, quantity, returned, unit_price 
+				 FROM order_lines 
+				 WHERE order_id = $1 
+				 ORDER BY item_type, variant 
+				 FOR UPDATE`
+		err = tx.Select(ctx, &lines, query, orderID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to lock order lines")
+		}
+
+		for _, line := range lines {
+			if line.Quantity > line.Returned {
+				err = putBackStock(ctx, tx, line.ItemType, line.Variant, line.Quantity-line.Returned)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = lockAccount(ctx, tx, buyerID)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if line.Quantity > line.Returned {
+				_, err = refundOrderLine(ctx, tx, buyerID, holderID, orderID, line, line.Quantity-line.Returned)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		order, err = getOrder(ctx, tx, orderID)
+		return err
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return order, nil
+}
+
+// changeOrderStatus is a compare-and-set on the status, so two admins acting on
+// the same order cannot both move it out of the same state.
+func changeOrderStatus(ctx context.Context, tx postgres.Tx, orderID int64, from, to string, changedBy uuid.UUID) error {
+	query := `UPDATE orders 
+			  SET status = $3, status_changed_at = CURRENT_TIMESTAMP, status_changed_by = $4, 
+				  picked_up_at = CASE WHEN $3 = 'picked_up' THEN CURRENT_TIMESTAMP ELSE picked_up_at END 
+			  WHERE id = $1 AND status = $2`
+	tag, err := tx.Exec(ctx, query, orderID, from, to, changedBy)
+	if err != nil {
+		return errors.WithMessage(err, "failed to update order status")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvalidOrderTransition
+	}
+
+	return nil
+}
+
+func getOrder(ctx context.Context, tx postgres.Tx, orderID int64) (*entity.Order, error) {
+	orders := make([]entity.Order, 1)
+	query := `SELECT o.id, o.user_id, u.username, o.total, o.status, o.created_at 
+			  FROM orders o 
+			  JOIN users u ON u.id = o.user_id 
+			  WHERE o.id = $1`
+	err := tx.Get(ctx, &orders[0], query, orderID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get order")
+	}
+
+	if err = loadOrderLines(ctx, tx, orders); err != nil {
+		return nil, err
+	}
+
+	return &orders[0], nil
+}
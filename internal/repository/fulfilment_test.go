@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_OrderFulfilment(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db, 100)
+	admin := createTestUser(t, db, entity.Coin)
+
+	handedOut, _, err := repo.BuyItem(ctx, user.Id, "cup", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, entity.OrderCreated, handedOut.Status)
+	cancelled, _, err := repo.Checkout(ctx, user.Id, []entity.OrderLine{{ItemType: "pen", Quantity: 2}, {ItemType: "book", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, userCoins(t, db, user.Id))
+
+	open, err := repo.ListOrders(ctx, entity.OrderFilter{UserId: &user.Id, Statuses: []string{entity.OrderCreated, entity.OrderReady}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, open, 2)
+	require.Equal(t, user.Username, open[0].Username)
+
+	order, err := repo.SetOrderStatus(ctx, handedOut.Id, entity.OrderCreated, entity.OrderReady, admin.Id)
+	require.NoError(t, err)
+	require.Equal(t, entity.OrderReady, order.Status)
+
+	// The compare-and-set rejects a change from a status the order has already left.
+	_, err = repo.SetOrderStatus(ctx, handedOut.Id, entity.OrderCreated, entity.OrderReady, admin.Id)
+	require.ErrorIs(t, err, domain.ErrInvalidOrderTransition)
+
+	_, err = repo.SetOrderStatus(ctx, handedOut.Id, entity.OrderReady, entity.OrderPickedUp, admin.Id)
+	require.NoError(t, err)
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: handedOut.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: time.Now().Add(-time.Hour)}, nil)
+	require.ErrorIs(t, err, domain.ErrOrderPickedUp)
+
+	// Cancelling refunds only what has not been returned already.
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: cancelled.Id, ItemType: "pen", Quantity: 1, PurchasedAfter: time.Now().Add(-time.Hour)}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 20, userCoins(t, db, user.Id))
+
+	order, err = repo.CancelOrder(ctx, cancelled.Id, entity.OrderCreated, admin.Id)
+	require.NoError(t, err)
+	require.Equal(t, entity.OrderCancelled, order.Status)
+	for _, line := range order.Lines {
+		require.Equal(t, line.Quantity, line.Returned)
+	}
+	require.EqualValues(t, 80, userCoins(t, db, user.Id))
+
+	info, err := repo.GetInfo(ctx, user.Id, 10)
+	require.NoError(t, err)
+	require.Equal(t, []entity.Item{{Type: "cup", Quantity: 1}}, info.Inventory)
+	require.Len(t, info.Refunds, 3)
+
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: cancelled.Id, ItemType: "book", Quantity: 1, PurchasedAfter: time.Now().Add(-time.Hour)}, nil)
+	require.ErrorIs(t, err, domain.ErrOrderCancelled)
+
+	open, err = repo.ListOrders(ctx, entity.OrderFilter{UserId: &user.Id, Statuses: []string{entity.OrderCreated, entity.OrderReady}, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, open)
+}
+
+func TestTransaction_CancelGiftOrder(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	sender := createTestUser(t, db, 100)
+	recipient := createTestUser(t, db, 100)
+	admin := createTestUser(t, db, entity.Coin)
+
+	gift, _, err := repo.SendGift(ctx, sender.Id, entity.SendGift{ToUser: recipient.Username, ItemType: "cup", Quantity: 2, Source: entity.GiftSourcePurchase}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 60, userCoins(t, db, sender.Id))
+
+	_, err = repo.CancelOrder(ctx, gift.Id, entity.OrderCreated, admin.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, userCoins(t, db, sender.Id))
+
+	info, err := repo.GetInfo(ctx, recipient.Id, 10)
+	require.NoError(t, err)
+	require.Empty(t, info.Inventory)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// SendGift gives an item to another user. A purchased gift is paid for by the
+// sender like any order and returned; a gift from the inventory moves items the
+// sender already owns and returns a nil order.
+func (t Transaction) SendGift(
+	ctx context.Context,
+	userID uuid.UUID,
+	gift entity.SendGift,
+	idempotencyKey *entity.IdempotencyKey,
+) (*entity.Order, *entity.IdempotencyKey, error) {
+	var (
+		order  *entity.Order
+		replay *entity.IdempotencyKey
+	)
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		var err error
+		replay, err = claimIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil || replay != nil {
+			return err
+		}
+
+		// The parties are not locked here: placeOrder locks the buyer after the items,
+		// and locking users first would invert that order.
+		var parties []struct {
+			Id       uuid.UUID
+			Username string
+		}
+		query := `SELECT id, username FROM users WHERE id = $1 OR username = $2`
+		err = tx.Select(ctx, &parties, query, userID, gift.ToUser)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get gift parties")
+		}
+
+		var recipientID uuid.UUID
+		for _, party := range parties {
+			if party.Username != gift.ToUser {
+				continue
+			}
+			if party.Id == userID {
+				return domain.ErrSelfGift
+			}
+			recipientID = party.Id
+		}
+		if recipientID == uuid.Nil {
+			return domain.ErrRecipientNotFound
+		}
+
+		order = nil
+		var orderID *int64
+		switch gift.Source {
+		case entity.GiftSourceInventory:
+			err = takeUserItems(ctx, tx, userID, gift.ItemType, gift.Variant, gift.Quantity)
+			if err != nil {
+				return err
+			}
+			err = addUserItems(ctx, tx, recipientID, gift.ItemType, gift.Variant, gift.Quantity)
+		default:
+			order, err = placeOrder(ctx, tx, userID, recipientID, []entity.OrderLine{
+				{ItemType: gift.ItemType, Variant: gift.Variant, Quantity: gift.Quantity},
+			})
+			if order != nil {
+				orderID = &order.Id
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO gifts (from_user, to_user, item_type, variant, quantity, source, order_id, message) 
+				 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)`
+		_, err = tx.Exec(ctx, query, userID, recipientID, gift.ItemType, gift.Variant, gift.Quantity, gift.Source, orderID, gift.Message)
+		if err != nil {
+			return errors.WithMessage(err, "failed to record gift")
+		}
+
+		return nil
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return order, replay, nil
+}
+
+// takeUserItems removes quantity items from the user's inventory, dropping the
+// row once none are left.
+func takeUserItems(ctx context.Context, tx postgres.Tx, userID uuid.UUID, itemType string, variant string, quantity int) error {
+	query := `UPDATE user_items 
+			  SET quantity = quantity - $4 
+			  WHERE user_id = $1 AND type = $2 AND variant IS NOT DISTINCT FROM NULLIF($3, '') AND quantity >= $4`
+	tag, err := tx.Exec(ctx, query, userID, itemType, variant, quantity)
+	if err != nil {
+		return errors.WithMessage(err, "failed to take user items")
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotEnoughItems
+	}
+
+	query = `DELETE FROM user_items 
+			 WHERE user_id = $1 AND type = $2 AND variant IS NOT DISTINCT FROM NULLIF($3, '') AND quantity = 0`
+	_, err = tx.Exec(ctx, query, userID, itemType, variant)
+	if err != nil {
+		return errors.WithMessage(err, "failed to remove empty user item")
+	}
+
+	return nil
+}
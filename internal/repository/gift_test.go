@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_SendGift(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, 100)
+	bob := createTestUser(t, db, 100)
+
+	_, _, err := repo.SendGift(ctx, alice.Id, entity.SendGift{ToUser: alice.Username, ItemType: "cup", Quantity: 1, Source: entity.GiftSourcePurchase}, nil)
+	require.ErrorIs(t, err, domain.ErrSelfGift)
+	_, _, err = repo.SendGift(ctx, alice.Id, entity.SendGift{ToUser: "no-such-user", ItemType: "cup", Quantity: 1, Source: entity.GiftSourcePurchase}, nil)
+	require.ErrorIs(t, err, domain.ErrRecipientNotFound)
+
+	// A purchased gift is paid by the sender and lands in the recipient's inventory.
+	order, _, err := repo.SendGift(ctx, alice.Id, entity.SendGift{
+		ToUser:   bob.Username,
+		ItemType: "cup",
+		Quantity: 2,
+		Source:   entity.GiftSourcePurchase,
+		Message:  "thanks for the review",
+	}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, order)
+	require.EqualValues(t, 40, order.Total)
+	require.EqualValues(t, 60, userCoins(t, db, alice.Id))
+	require.EqualValues(t, 100, userCoins(t, db, bob.Id))
+
+	// Bob passes one of the cups on from his inventory, but cannot give more than he owns.
+	_, _, err = repo.SendGift(ctx, bob.Id, entity.SendGift{ToUser: alice.Username, ItemType: "cup", Quantity: 3, Source: entity.GiftSourceInventory}, nil)
+	require.ErrorIs(t, err, domain.ErrNotEnoughItems)
+	order, _, err = repo.SendGift(ctx, bob.Id, entity.SendGift{ToUser: alice.Username, ItemType: "cup", Quantity: 1, Source: entity.GiftSourceInventory}, nil)
+	require.NoError(t, err)
+	require.Nil(t, order)
+	require.EqualValues(t, 100, userCoins(t, db, bob.Id))
+
+	aliceInfo, err := repo.GetInfo(ctx, alice.Id, 10)
+	require.NoError(t, err)
+	require.Equal(t, []entity.Item{{Type: "cup", Quantity: 1}}, aliceInfo.Inventory)
+	require.Len(t, aliceInfo.Gifted, 1)
+	require.Equal(t, "thanks for the review", aliceInfo.Gifted[0].Message)
+	require.NotNil(t, aliceInfo.Gifted[0].OrderId)
+	require.Len(t, aliceInfo.ReceivedGifts, 1)
+	require.Equal(t, bob.Username, aliceInfo.ReceivedGifts[0].FromUser)
+	require.Equal(t, entity.GiftSourceInventory, aliceInfo.ReceivedGifts[0].Source)
+
+	bobInfo, err := repo.GetInfo(ctx, bob.Id, 10)
+	require.NoError(t, err)
+	require.Equal(t, []entity.Item{{Type: "cup", Quantity: 1}}, bobInfo.Inventory)
+	require.Empty(t, bobInfo.RecentPurchases)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"time"
+)
+
+type Idempotency struct {
+	db postgres.Postgres
+}
+
+func NewIdempotency(db postgres.Postgres) Idempotency {
+	return Idempotency{
+		db: db,
+	}
+}
+
+func (i Idempotency) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+	tag, err := postgres.Conn(ctx, i.db).Exec(ctx, query, before)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to delete expired idempotency keys")
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// claimIdempotencyKey records the key together with its response inside tx, so
+// the record only survives if the operation commits. When the key has already
+// been used it returns the stored record instead, and the caller must skip the
+// operation. A nil key disables the check.
+func claimIdempotencyKey(ctx context.Context, tx postgres.Tx, key *entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	if key == nil {
+		return nil, nil // nolint: nilnil
+	}
+
+	query := `INSERT INTO idempotency_keys (user_id, key, request_hash, status_code, response) 
+			  VALUES ($1, $2, $3, $4, $5) 
+			  ON CONFLICT (user_id, key) DO NOTHING`
+	tag, err := tx.Exec(ctx, query, key.UserId, key.Key, key.RequestHash, key.StatusCode, key.Response)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to store idempotency key")
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil // nolint: nilnil
+	}
+
+	var stored entity.IdempotencyKey
+	query = `SELECT user_id, key, request_hash, status_code, response 
+			 FROM idempotency_keys 
+			 WHERE user_id = $1 AND key = $2`
+	err = tx.Get(ctx, &stored, query, key.UserId, key.Key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get idempotency key")
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		return nil, domain.ErrIdempotencyKeyMismatch
+	}
+
+	return &stored, nil
+}
+
+// storeIdempotentResponse replaces the response recorded by claimIdempotencyKey
+// once it is known, inside the same transaction.
+func storeIdempotentResponse(ctx context.Context, tx postgres.Tx, key *entity.IdempotencyKey, response []byte) error {
+	if key == nil {
+		return nil
+	}
+
+	query := `UPDATE idempotency_keys SET response = $3 WHERE user_id = $1 AND key = $2`
+	_, err := tx.Exec(ctx, query, key.UserId, key.Key, response)
+	if err != nil {
+		return errors.WithMessage(err, "failed to store idempotent response")
+	}
+
+	return nil
+}
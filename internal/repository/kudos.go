@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"fmt"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strings"
+)
+
+type Kudos struct {
+	db postgres.Postgres
+}
+
+func NewKudos(db postgres.Postgres) Kudos {
+	return Kudos{
+		db: db,
+	}
+}
+
+// ListKudos returns one page of the public thank-yous, newest first, fetching
+// up to filter.Limit+1 rows so the caller can tell whether another page exists.
+// The conditions repeat the predicate of coin_transactions_kudos_idx so the
+// feed is served from that index.
+func (k Kudos) ListKudos(ctx context.Context, filter entity.KudosFilter) ([]entity.Kudos, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"NOT private", "(category IS NOT NULL OR message <> '')"}
+	if filter.Category != "" {
+		conditions = append(conditions, "category = "+arg(filter.Category))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s)", arg(filter.After.CreatedAt), arg(filter.After.Id)))
+	}
+
+	var kudos []entity.Kudos
+	query := `SELECT id, COALESCE(from_user, '') AS from_user, COALESCE(to_user, '') AS to_user, 
+			  message, COALESCE(category, '') AS category, created_at 
+			  FROM coin_transactions 
+			  WHERE ` + strings.Join(conditions, " AND ") + ` 
+			  ORDER BY created_at DESC, id DESC 
+			  LIMIT ` + arg(filter.Limit+1)
+	err := postgres.Conn(ctx, k.db).Select(ctx, &kudos, query, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list kudos")
+	}
+
+	return kudos, nil
+}
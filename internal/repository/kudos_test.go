@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKudos_ListKudos(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	kudos := NewKudos(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, 100)
+	bob := createTestUser(t, db, 100)
+
+	sends := []entity.SendCoin{
+		{ToUser: bob.Username, Amount: 1},
+		{ToUser: bob.Username, Amount: 2, Message: "thanks for the pairing", Category: entity.KudosHelpedMe},
+		{ToUser: bob.Username, Amount: 3, Message: "just between us", Category: entity.KudosTeamwork, Private: true},
+		{ToUser: bob.Username, Amount: 4, Category: entity.KudosOnboarding},
+	}
+	for _, send := range sends {
+		_, err := repo.SendCoin(ctx, alice.Id, send, nil)
+		require.NoError(t, err)
+	}
+
+	// Other tests share the database, so only alice's thank-yous are compared.
+	var feed []entity.Kudos
+	filter := entity.KudosFilter{Limit: 100}
+	page, err := kudos.ListKudos(ctx, filter)
+	require.NoError(t, err)
+	for _, item := range page {
+		if item.FromUser == alice.Username {
+			feed = append(feed, item)
+		}
+	}
+	require.Len(t, feed, 2)
+	require.Equal(t, entity.KudosOnboarding, feed[0].Category)
+	require.Empty(t, feed[0].Message)
+	require.Equal(t, "thanks for the pairing", feed[1].Message)
+	require.Equal(t, bob.Username, feed[1].ToUser)
+
+	filter.Category = entity.KudosTeamwork
+	page, err = kudos.ListKudos(ctx, filter)
+	require.NoError(t, err)
+	for _, item := range page {
+		require.NotEqual(t, alice.Username, item.FromUser)
+	}
+
+	history, err := repo.GetHistory(ctx, bob.Id, entity.HistoryFilter{Direction: domain.DirectionReceived, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	require.Equal(t, "just between us", history[1].Message)
+	require.Equal(t, entity.KudosTeamwork, history[1].Category)
+	require.True(t, history[1].Private)
+	require.Empty(t, history[3].Category)
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var errUnbalancedEntry = errors.New("journal entry does not balance")
+
+type Ledger struct {
+	db postgres.Postgres
+}
+
+func NewLedger(db postgres.Postgres) Ledger {
+	return Ledger{
+		db: db,
+	}
+}
+
+// Balance sums the postings of a user's account.
+func (l Ledger) Balance(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var balance int64
+	query := `SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id = $1`
+	err := postgres.Conn(ctx, l.db).Get(ctx, &balance, query, userID)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to get ledger balance")
+	}
+
+	return balance, nil
+}
+
+// RebuildBalances recomputes the users.coin projection from the ledger and
+// returns the number of users whose cached balance was wrong. Every user row is
+// locked first, in the id order transfers lock them, so no coin movement can
+// commit between summing an account's postings and rewriting its balance.
+func (l Ledger) RebuildBalances(ctx context.Context) (int64, error) {
+	var rebuilt int64
+
+	err := postgres.ExecTx(ctx, l.db, func(tx postgres.Tx) error {
+		query := `SELECT 1 FROM users ORDER BY id FOR UPDATE`
+		_, err := tx.Exec(ctx, query)
+		if err != nil {
+			return errors.WithMessage(err, "failed to lock accounts")
+		}
+
+		query = `UPDATE users u 
+				  SET coin = b.balance 
+				  FROM (
+					  SELECT a.id, COALESCE(SUM(p.amount), 0) AS balance 
+					  FROM ledger_accounts a 
+					  LEFT JOIN postings p ON p.account_id = a.id 
+					  WHERE a.kind = 'user' 
+					  GROUP BY a.id
+				  ) b 
+				  WHERE u.id = b.id AND u.coin <> b.balance`
+		tag, err := tx.Exec(ctx, query)
+		if err != nil {
+			return errors.WithMessage(err, "failed to rebuild balances")
+		}
+
+		rebuilt = tag.RowsAffected()
+		return nil
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "transaction failed")
+	}
+
+	return rebuilt, nil
+}
+
+// FindDrift recomputes every balance from its grants, coin_transactions, orders
+// and refunds and returns the users whose cached or ledger balance differs.
+// Transfers made before the ledger existed are already part of the opening
+// balances, so only later ones are counted.
+func (l Ledger) FindDrift(ctx context.Context) ([]entity.BalanceDrift, error) {
+	var drift []entity.BalanceDrift
+
+	err := postgres.ExecTx(ctx, l.db, func(tx postgres.Tx) error {
+		drift = nil
+
+		query := `WITH ledger_start AS (
+					  SELECT created_at FROM ledger_accounts WHERE id = $1
+				  ), 
+				  balances AS (
+					  SELECT u.id AS user_id, u.username, u.coin AS cached, 
+						  (SELECT COALESCE(SUM(p.amount), 0) FROM postings p WHERE p.account_id = u.id) AS ledger, 
+						  (SELECT COALESCE(SUM(p.amount), 0) 
+						   FROM postings p 
+						   JOIN journal_entries e ON e.id = p.entry_id 
+						   WHERE p.account_id = u.id AND e.kind IN ('opening_balance', 'grant')) AS granted, 
+						  (SELECT COALESCE(SUM(t.amount), 0) 
+						   FROM coin_transactions t, ledger_start s 
+						   WHERE t.to_user = u.username AND t.created_at >= s.created_at) AS received, 
+						  (SELECT COALESCE(SUM(t.amount), 0) 
+						   FROM coin_transactions t, ledger_start s 
+						   WHERE t.from_user = u.username AND t.created_at >= s.created_at) AS sent, 
+						  (SELECT COALESCE(SUM(o.total), 0) FROM orders o WHERE o.user_id = u.id) - 
+						  (SELECT COALESCE(SUM(r.amount), 0) FROM refunds r WHERE r.user_id = u.id) AS spent 
+					  FROM users u
+				  ) 
+				  SELECT user_id, username, cached, ledger, granted, received, sent, spent, 
+					  granted + received - sent - spent AS expected, 
+					  cached - (granted + received - sent - spent) AS drift 
+				  FROM balances 
+				  WHERE cached <> granted + received - sent - spent OR ledger <> granted + received - sent - spent 
+				  ORDER BY username`
+		err := tx.Select(ctx, &drift, query, entity.RevenueAccountID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to compute balance drift")
+		}
+
+		return nil
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return drift, nil
+}
+
+// CorrectBalance posts an adjustment entry that brings the ledger balance to the
+// recomputed value and moves users.coin along with it. It reports false when the
+// balance changed since the drift was computed, leaving it untouched.
+func (l Ledger) CorrectBalance(ctx context.Context, drift entity.BalanceDrift) (bool, error) {
+	var corrected bool
+
+	err := postgres.ExecTx(ctx, l.db, func(tx postgres.Tx) error {
+		corrected = false
+
+		var cached int64
+		query := `SELECT coin FROM users WHERE id = $1 FOR UPDATE`
+		err := tx.Get(ctx, &cached, query, drift.UserId)
+		if err != nil {
+			return errors.WithMessage(err, "failed to lock account")
+		}
+
+		var balance int64
+		query = `SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id = $1`
+		err = tx.Get(ctx, &balance, query, drift.UserId)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get ledger balance")
+		}
+
+		if cached != drift.Cached || balance != drift.Ledger {
+			return nil
+		}
+
+		if adjustment := drift.Expected - drift.Ledger; adjustment != 0 {
+			_, err = postJournalEntry(ctx, tx, entity.JournalAdjustment, drift.UserId.String(),
+				entity.Posting{AccountId: entity.IssuanceAccountID, Amount: -adjustment},
+				entity.Posting{AccountId: drift.UserId, Amount: adjustment},
+			)
+			if err != nil {
+				return errors.WithMessage(err, "failed to post adjustment")
+			}
+		}
+
+		query = `UPDATE users SET coin = coin + $1 WHERE id = $2`
+		_, err = tx.Exec(ctx, query, drift.Expected-drift.Cached, drift.UserId)
+		if err != nil {
+			return errors.WithMessage(err, "failed to update balance")
+		}
+
+		corrected = true
+		return nil
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return false, errors.Wrap(err, "transaction failed")
+	}
+
+	return corrected, nil
+}
+
+func createLedgerAccount(ctx context.Context, tx postgres.Tx, userID uuid.UUID) error {
+	query := `INSERT INTO ledger_accounts (id, kind) VALUES ($1, $2)`
+	_, err := tx.Exec(ctx, query, userID, entity.LedgerAccountUser)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create ledger account")
+	}
+
+	return nil
+}
+
+// postJournalEntry appends a balanced entry. Callers still update the users.coin
+// projection themselves, in the same transaction.
+func postJournalEntry(ctx context.Context, tx postgres.Tx, kind, reference string, postings ...entity.Posting) (int64, error) {
+	var total int64
+	for _, posting := range postings {
+		total += posting.Amount
+	}
+	if total != 0 || len(postings) < 2 {
+		return 0, errUnbalancedEntry
+	}
+
+	var entryID int64
+	query := `INSERT INTO journal_entries (kind, reference) VALUES ($1, $2) RETURNING id`
+	err := tx.Get(ctx, &entryID, query, kind, reference)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to create journal entry")
+	}
+
+	for _, posting := range postings {
+		query = `INSERT INTO postings (entry_id, account_id, amount) VALUES ($1, $2, $3)`
+		_, err = tx.Exec(ctx, query, entryID, posting.AccountId, posting.Amount)
+		if err != nil {
+			return 0, errors.WithMessage(err, "failed to create posting")
+		}
+	}
+
+	return entryID, nil
+}
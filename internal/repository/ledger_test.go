@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger_ProjectionMatchesPostings(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ledger := NewLedger(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, entity.Coin)
+	bob := createTestUser(t, db, entity.Coin)
+
+	_, err := repo.SendCoin(ctx, alice.Id, entity.SendCoin{ToUser: bob.Username, Amount: 150}, nil)
+	require.NoError(t, err)
+	_, _, err = repo.BuyItem(ctx, bob.Id, "book", "", nil)
+	require.NoError(t, err)
+
+	for _, user := range []entity.Auth{alice, bob} {
+		balance, err := ledger.Balance(ctx, user.Id)
+		require.NoError(t, err)
+		require.Equal(t, userCoins(t, db, user.Id), balance)
+	}
+
+	var unbalanced int64
+	err = db.Get(ctx, &unbalanced, `SELECT count(*) FROM (
+		SELECT entry_id FROM postings
+		WHERE entry_id IN (SELECT entry_id FROM postings WHERE account_id = ANY($1))
+		GROUP BY entry_id HAVING SUM(amount) <> 0
+	) e`, []uuid.UUID{alice.Id, bob.Id})
+	require.NoError(t, err)
+	require.Zero(t, unbalanced)
+
+	_, err = db.Exec(ctx, `UPDATE users SET coin = 1 WHERE id = $1`, alice.Id)
+	require.NoError(t, err)
+
+	drift, err := ledger.FindDrift(ctx)
+	require.NoError(t, err)
+	var found *entity.BalanceDrift
+	for i := range drift {
+		if drift[i].UserId == alice.Id {
+			found = &drift[i]
+		}
+	}
+	require.NotNil(t, found)
+	require.Equal(t, entity.BalanceDrift{
+		UserId:   alice.Id,
+		Username: alice.Username,
+		Cached:   1,
+		Ledger:   entity.Coin - 150,
+		Granted:  entity.Coin,
+		Sent:     150,
+		Expected: entity.Coin - 150,
+		Drift:    1 - (entity.Coin - 150),
+	}, *found)
+
+	// The ledger already holds the right balance, so only users.coin moves.
+	corrected, err := ledger.CorrectBalance(ctx, *found)
+	require.NoError(t, err)
+	require.True(t, corrected)
+	require.EqualValues(t, entity.Coin-150, userCoins(t, db, alice.Id))
+	require.Zero(t, adjustments(t, db, alice.Id))
+
+	corrected, err = ledger.CorrectBalance(ctx, *found)
+	require.NoError(t, err)
+	require.False(t, corrected, "a stale drift must not be applied twice")
+
+	_, err = db.Exec(ctx, `UPDATE users SET coin = 1 WHERE id = $1`, alice.Id)
+	require.NoError(t, err)
+
+	rebuilt, err := ledger.RebuildBalances(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, rebuilt, int64(1))
+	require.EqualValues(t, entity.Coin-150, userCoins(t, db, alice.Id))
+
+	_, err = db.Exec(ctx, `DELETE FROM postings WHERE account_id = $1`, alice.Id)
+	require.Error(t, err, "ledger must be append-only")
+}
+
+func TestLedger_RebuildBalancesDuringTransfers(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ledger := NewLedger(db)
+	ctx := context.Background()
+
+	const rounds = 50
+
+	alice := createTestUser(t, db, entity.Coin)
+	bob := createTestUser(t, db, entity.Coin)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := repo.SendCoin(ctx, alice.Id, entity.SendCoin{ToUser: bob.Username, Amount: 1}, nil); err != nil {
+				t.Errorf("unexpected send error: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds/10; i++ {
+			if _, err := ledger.RebuildBalances(ctx); err != nil {
+				t.Errorf("unexpected rebuild error: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	// A rebuild that raced a transfer would have written a stale sum back.
+	require.EqualValues(t, entity.Coin-rounds, userCoins(t, db, alice.Id))
+	require.EqualValues(t, entity.Coin+rounds, userCoins(t, db, bob.Id))
+}
+
+func TestLedger_FindDriftFromSourceTables(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ledger := NewLedger(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, entity.Coin)
+	bob := createTestUser(t, db, entity.Coin)
+
+	_, err := repo.SendCoin(ctx, alice.Id, entity.SendCoin{ToUser: bob.Username, Amount: 150}, nil)
+	require.NoError(t, err)
+
+	// Cache and ledger agree with each other but not with coin_transactions.
+	_, err = db.Exec(ctx, `DELETE FROM coin_transactions WHERE from_user = $1`, alice.Username)
+	require.NoError(t, err)
+
+	drift, err := ledger.FindDrift(ctx)
+	require.NoError(t, err)
+	found := make(map[uuid.UUID]entity.BalanceDrift)
+	for _, d := range drift {
+		found[d.UserId] = d
+	}
+	require.Equal(t, entity.BalanceDrift{
+		UserId:   alice.Id,
+		Username: alice.Username,
+		Cached:   entity.Coin - 150,
+		Ledger:   entity.Coin - 150,
+		Granted:  entity.Coin,
+		Expected: entity.Coin,
+		Drift:    -150,
+	}, found[alice.Id])
+	require.Contains(t, found, bob.Id)
+
+	corrected, err := ledger.CorrectBalance(ctx, found[alice.Id])
+	require.NoError(t, err)
+	require.True(t, corrected)
+	require.EqualValues(t, entity.Coin, userCoins(t, db, alice.Id))
+	require.EqualValues(t, 150, adjustments(t, db, alice.Id))
+
+	balance, err := ledger.Balance(ctx, alice.Id)
+	require.NoError(t, err)
+	require.EqualValues(t, entity.Coin, balance)
+
+	drift, err = ledger.FindDrift(ctx)
+	require.NoError(t, err)
+	for _, d := range drift {
+		require.NotEqual(t, alice.Id, d.UserId)
+	}
+}
+
+func adjustments(t *testing.T, db postgres.Postgres, userID uuid.UUID) int64 {
+	t.Helper()
+
+	var total int64
+	err := db.Get(context.Background(), &total, `SELECT COALESCE(SUM(p.amount), 0) 
+		FROM postings p 
+		JOIN journal_entries e ON e.id = p.entry_id 
+		WHERE p.account_id = $1 AND e.kind = 'adjustment'`, userID)
+	require.NoError(t, err)
+	return total
+}
@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strconv"
+)
+
+// Checkout prices, debits and fulfils all lines as one order. Any unknown item
+// or a short balance rejects the whole order.
+func (t Transaction) Checkout(
+	ctx context.Context,
+	userID uuid.UUID,
+	lines []entity.OrderLine,
+	idempotencyKey *entity.IdempotencyKey,
+) (*entity.Order, *entity.IdempotencyKey, error) {
+	var (
+		order  *entity.Order
+		replay *entity.IdempotencyKey
+	)
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		var err error
+		replay, err = claimIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil || replay != nil {
+			return err
+		}
+
+		order, err = placeOrder(ctx, tx, userID, userID, lines)
+		if err != nil {
+			return err
+		}
+
+		// The replayed response is rebuilt from the order, so only its id is stored.
+		return storeIdempotentResponse(ctx, tx, idempotencyKey, []byte(strconv.FormatInt(order.Id, 10)))
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return order, replay, nil
+}
+
+// AuditOrder records who placed an order and how. It joins the transaction in ctx,
+// so it is written together with the order or not at all.
+func (t Transaction) AuditOrder(ctx context.Context, userID uuid.UUID, orderID int64, action string) error {
+	query := `INSERT INTO order_audit (order_id, user_id, action) VALUES ($1, $2, $3)`
+	_, err := postgres.Conn(ctx, t.db).Exec(ctx, query, orderID, userID, action)
+	if err != nil {
+		return errors.WithMessage(err, "failed to audit order")
+	}
+
+	return nil
+}
+
+func (t Transaction) GetOrder(ctx context.Context, userID uuid.UUID, orderID int64) (*entity.Order, error) {
+	var orders []entity.Order
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		query := `SELECT id, user_id, total, status, created_at 
+				  FROM orders 
+				  WHERE id = $1 AND user_id = $2`
+		err := tx.Select(ctx, &orders, query, orderID, userID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get order")
+		}
+		if len(orders) == 0 {
+			return domain.ErrOrderNotFound
+		}
+
+		return loadOrderLines(ctx, tx, orders)
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return &orders[0], nil
+}
+
+// GetOrders returns one page of the user's orders, newest first, fetching up to
+// limit+1 rows so the caller can tell whether another page exists.
+func (t Transaction) GetOrders(ctx context.Context, userID uuid.UUID, after *entity.PageCursor, limit int) ([]entity.Order, error) {
+	var orders []entity.Order
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		orders = nil
+
+		query := `SELECT id, user_id, total, status, created_at 
+				  FROM orders 
+				  WHERE user_id = $1 
+				  ORDER BY created_at DESC, id DESC 
+				  LIMIT $2`
+		args := []any{userID, limit + 1}
+		if after != nil {
+			query = `SELECT id, user_id, total, status, created_at 
+					 FROM orders 
+					 WHERE user_id = $1 AND (created_at, id) < ($3, $4) 
+					 ORDER BY created_at DESC, id DESC 
+					 LIMIT $2`
+			args = append(args, after.CreatedAt, after.Id)
+		}
+
+		err := tx.Select(ctx, &orders, query, args...)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get orders")
+		}
+
+		return loadOrderLines(ctx, tx, orders)
+	}, snapshotTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return orders, nil
+}
+
+// placeOrder prices the lines at current catalog prices, debits the buyer,
+// records the order with its journal entry and adds the items to the
+// recipient's inventory, which is the buyer's own unless the order is a gift.
+func placeOrder(ctx context.Context, tx postgres.Tx, userID uuid.UUID, recipientID uuid.UUID, lines []entity.OrderLine) (*entity.Order, error) {
+	itemTypes := make([]string, 0, len(lines))
+	var skus []string
+	for _, line := range lines {
+		itemTypes = append(itemTypes, line.ItemType)
+		if line.Variant != "" {
+			skus = append(skus, line.Variant)
+		}
+	}
+
+	items, err := lockOrderItems(ctx, tx, itemTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := lockOrderVariants(ctx, tx, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	order, stockChanges, err := priceOrderLines(userID, lines, items, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the buyer's row so concurrent purchases and transfers are serialized on the balance.
+	var coin int64
+	query := `SELECT coin 
+			  FROM users 
+			  WHERE id = $1 
+			  FOR UPDATE`
+	err = tx.Get(ctx, &coin, query, userID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get user by ID")
+	}
+
+	if coin < order.Total {
+		return nil, domain.ErrInsufficientFunds
+	}
+
+	query = `INSERT INTO orders (user_id, total) 
+			 VALUES ($1, $2) 
+			 RETURNING id, status, created_at`
+	err = tx.Get(ctx, order, query, userID, order.Total)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create order")
+	}
+
+	for i := range order.Lines {
+		order.Lines[i].OrderId = order.Id
+		line := order.Lines[i]
+
+		query = `INSERT INTO order_lines (order_id, item_type, variant, quantity, unit_price) 
+				 VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+		_, err = tx.Exec(ctx, query, order.Id, line.ItemType, line.Variant, line.Quantity, line.UnitPrice)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to create order line")
+		}
+
+		err = addUserItems(ctx, tx, recipientID, line.ItemType, line.Variant, line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order.LowStock, err = takeStock(ctx, tx, stockChanges)
+	if err != nil {
+		return nil, err
+	}
+
+	// Free orders move no coins and need no journal entry.
+	if order.Total > 0 {
+		entryID, err := postJournalEntry(ctx, tx, entity.JournalPurchase, strconv.FormatInt(order.Id, 10),
+			entity.Posting{AccountId: userID, Amount: -order.Total},
+			entity.Posting{AccountId: entity.RevenueAccountID, Amount: order.Total},
+		)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to record purchase")
+		}
+
+		query = `UPDATE orders SET journal_entry_id = $1 WHERE id = $2`
+		_, err = tx.Exec(ctx, query, entryID, order.Id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to link order to journal entry")
+		}
+
+		query = `UPDATE users 
+				 SET coin = coin - $1 
+				 WHERE id = $2`
+		_, err = tx.Exec(ctx, query, order.Total, userID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to update user coins")
+		}
+	}
+
+	return order, nil
+}
+
+func addUserItems(ctx context.Context, tx postgres.Tx, userID uuid.UUID, itemType string, variant string, quantity int) error {
+	query := `INSERT INTO user_items (user_id, type, variant, quantity) 
+			  VALUES ($1, $2, NULLIF($3, ''), $4) 
+			  ON CONFLICT (user_id, type, variant) 
+			  DO UPDATE SET quantity = user_items.quantity + EXCLUDED.quantity`
+	_, err := tx.Exec(ctx, query, userID, itemType, variant, quantity)
+	if err != nil {
+		return errors.WithMessage(err, "failed to add user item")
+	}
+
+	return nil
+}
+
+type orderItem struct {
+	Type              string
+	Price             int64
+	Available         bool
+	Retired           bool
+	HasVariants       bool
+	Stock             *int
+	LowStockThreshold *int
+}
+
+type orderVariant struct {
+	Sku      string
+	ItemType string
+	Price    *int64
+	Stock    *int
+}
+
+// stockChange is one line's draw on a limited stock, either the item's or,
+// when Variant is set, the variant's own.
+type stockChange struct {
+	ItemType  string
+	Variant   string
+	Before    int
+	After     int
+	Threshold *int
+}
+
+// priceOrderLines checks the lines against the locked catalog rows and works out
+// what each line costs and which stock it draws on. Lines sharing a stock are
+// counted against it in turn.
+func priceOrderLines(
+	userID uuid.UUID,
+	lines []entity.OrderLine,
+	items []orderItem,
+	variants []orderVariant,
+) (*entity.Order, []stockChange, error) {
+	byType := make(map[string]orderItem, len(items))
+	for _, item := range items {
+		if item.Retired {
+			return nil, nil, domain.ErrItemRetired
+		}
+		if !item.Available {
+			return nil, nil, domain.ErrItemUnavailable
+		}
+		byType[item.Type] = item
+	}
+
+	bySku := make(map[string]orderVariant, len(variants))
+	for _, variant := range variants {
+		bySku[variant.Sku] = variant
+	}
+
+	// Remaining stock per item, or per variant for variants with their own stock.
+	remaining := make(map[string]int)
+
+	order := entity.Order{
+		UserId: userID,
+		Lines:  make([]entity.OrderLine, 0, len(lines)),
+	}
+	var changes []stockChange
+	for _, line := range lines {
+		item, ok := byType[line.ItemType]
+		if !ok {
+			return nil, nil, domain.ErrItemNotFound
+		}
+
+		price, stock, stockVariant := item.Price, item.Stock, ""
+		switch {
+		case line.Variant != "":
+			variant, ok := bySku[line.Variant]
+			if !ok || variant.ItemType != line.ItemType {
+				return nil, nil, domain.ErrVariantNotFound
+			}
+			if variant.Price != nil {
+				price = *variant.Price
+			}
+			if variant.Stock != nil {
+				stock, stockVariant = variant.Stock, variant.Sku
+			}
+		case item.HasVariants:
+			return nil, nil, domain.ErrVariantRequired
+		}
+
+		if stock != nil {
+			key := line.ItemType + "/" + stockVariant
+			before, seen := remaining[key]
+			if !seen {
+				before = *stock
+			}
+			if before < line.Quantity {
+				return nil, nil, domain.ErrOutOfStock
+			}
+			remaining[key] = before - line.Quantity
+
+			changes = append(changes, stockChange{
+				ItemType:  line.ItemType,
+				Variant:   stockVariant,
+				Before:    before,
+				After:     before - line.Quantity,
+				Threshold: item.LowStockThreshold,
+			})
+		}
+
+		line.UnitPrice = price
+		order.Lines = append(order.Lines, line)
+		order.Total += price * int64(line.Quantity)
+	}
+
+	return &order, changes, nil
+}
+
+// takeStock decrements the limited stocks and reports those that fell to
+// their low-stock threshold.
+func takeStock(ctx context.Context, tx postgres.Tx, changes []stockChange) ([]entity.StockLevel, error) {
+	var lowStock []entity.StockLevel
+	for _, change := range changes {
+		query := `UPDATE items 
+				  SET stock = stock - $2, updated_at = CURRENT_TIMESTAMP 
+				  WHERE type = $1`
+		key := change.ItemType
+		if change.Variant != "" {
+			query = `UPDATE item_variants 
+					 SET stock = stock - $2 
+					 WHERE sku = $1`
+			key = change.Variant
+		}
+		_, err := tx.Exec(ctx, query, key, change.Before-change.After)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to decrement stock")
+		}
+
+		if change.Threshold != nil && change.Before > *change.Threshold && change.After <= *change.Threshold {
+			lowStock = append(lowStock, entity.StockLevel{
+				ItemType:  change.ItemType,
+				Variant:   change.Variant,
+				Stock:     change.After,
+				Threshold: *change.Threshold,
+			})
+		}
+	}
+
+	return lowStock, nil
+}
+
+// lockOrderItems locks the items being bought until the order commits, so an admin
+// cannot reprice or retire them mid-purchase and the buyer is charged exactly the
+// price that is current at the moment of purchase. Items with limited stock are
+// locked FOR UPDATE in type order, because their stock is decremented later and
+// upgrading a shared lock would deadlock concurrent buyers. Items in unlimited
+// supply only need FOR SHARE and never block each other.
+func lockOrderItems(ctx context.Context, tx postgres.Tx, itemTypes []string) ([]orderItem, error) {
+	var limited, unlimited []orderItem
+
+	query := `SELECT type, price, available, retired_at IS NOT NULL AS retired, 
+			  EXISTS (SELECT 1 FROM item_variants WHERE item_id = items.id) AS has_variants, stock, low_stock_threshold 
+			  FROM items 
+			  WHERE type = ANY($1) AND stock IS NOT NULL 
+			  ORDER BY type 
+			  FOR UPDATE`
+	err := tx.Select(ctx, &limited, query, itemTypes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to lock limited items")
+	}
+
+	locked := make([]string, 0, len(limited))
+	for _, item := range limited {
+		locked = append(locked, item.Type)
+	}
+
+	// The rest is matched by exclusion rather than stock IS NULL, so an item that
+	// was restocked for the first time in between is still found.
+	query = `SELECT type, price, available, retired_at IS NOT NULL AS retired, 
+			 EXISTS (SELECT 1 FROM item_variants WHERE item_id = items.id) AS has_variants, stock, low_stock_threshold 
+			 FROM items 
+			 WHERE type = ANY($1) AND NOT type = ANY($2) 
+			 FOR SHARE`
+	err = tx.Select(ctx, &unlimited, query, itemTypes, locked)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get item prices")
+	}
+
+	return append(limited, unlimited...), nil
+}
+
+// lockOrderVariants locks the chosen variants the same way lockOrderItems locks items.
+func lockOrderVariants(ctx context.Context, tx postgres.Tx, skus []string) ([]orderVariant, error) {
+	if len(skus) == 0 {
+		return nil, nil
+	}
+
+	var limited, unlimited []orderVariant
+
+	query := `SELECT v.sku, i.type AS item_type, v.price, v.stock 
+			  FROM item_variants v 
+			  JOIN items i ON i.id = v.item_id 
+			  WHERE v.sku = ANY($1) AND v.stock IS NOT NULL 
+			  ORDER BY v.sku 
+			  FOR UPDATE OF v`
+	err := tx.Select(ctx, &limited, query, skus)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to lock limited variants")
+	}
+
+	locked := make([]string, 0, len(limited))
+	for _, variant := range limited {
+		locked = append(locked, variant.Sku)
+	}
+
+	query = `SELECT v.sku, i.type AS item_type, v.price, v.stock 
+			 FROM item_variants v 
+			 JOIN items i ON i.id = v.item_id 
+			 WHERE v.sku = ANY($1) AND NOT v.sku = ANY($2) 
+			 FOR SHARE OF v`
+	err = tx.Select(ctx, &unlimited, query, skus, locked)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get variant prices")
+	}
+
+	return append(limited, unlimited...), nil
+}
+
+func loadOrderLines(ctx context.Context, tx postgres.Tx, orders []entity.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(orders))
+	byID := make(map[int64]*entity.Order, len(orders))
+	for i := range orders {
+		ids = append(ids, orders[i].Id)
+		byID[orders[i].Id] = &orders[i]
+		orders[i].Lines = nil
+	}
+
+	var lines []entity.OrderLine
+	query := `SELECT order_id, item_type, COALESCE(variant, '') AS variant, quantity, unit_price, returned 
+			  FROM order_lines 
+			  WHERE order_id = ANY($1) 
+			  ORDER BY id`
+	err := tx.Select(ctx, &lines, query, ids)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get order lines")
+	}
+
+	for _, line := range lines {
+		order := byID[line.OrderId]
+		order.Lines = append(order.Lines, line)
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strings"
+)
+
+// paymentRequestStatus reports pending requests past their expires_at as expired.
+const paymentRequestStatus = `CASE WHEN r.status = 'pending' AND r.expires_at <= CURRENT_TIMESTAMP THEN 'expired' ELSE r.status END`
+
+func (t Transaction) CreatePaymentRequest(ctx context.Context, requesterID uuid.UUID, req entity.NewPaymentRequest) (*entity.PaymentRequest, error) {
+	var request *entity.PaymentRequest
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		var payers []uuid.UUID
+		query := `SELECT id FROM users WHERE username = $1`
+		err := tx.Select(ctx, &payers, query, req.Payer)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get payer")
+		}
+		if len(payers) == 0 {
+			return domain.ErrUserNotFound
+		}
+		if payers[0] == requesterID {
+			return domain.ErrSelfPaymentRequest
+		}
+
+		var id int64
+		query = `INSERT INTO payment_requests (requester_id, payer_id, amount, message, expires_at) 
+				 VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + $5::interval) 
+				 RETURNING id`
+		err = tx.Get(ctx, &id, query, requesterID, payers[0], req.Amount, req.Message, req.TTL)
+		if err != nil {
+			return errors.WithMessage(err, "failed to insert payment request")
+		}
+
+		request, err = getPaymentRequest(ctx, tx, id)
+		return err
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return request, nil
+}
+
+// ListPaymentRequests returns one page of the requests matching the filter, newest
+// first, fetching up to filter.Limit+1 rows so the caller can tell whether another page exists.
+func (t Transaction) ListPaymentRequests(ctx context.Context, filter entity.PaymentRequestFilter) ([]entity.PaymentRequest, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var conditions []string
+	if filter.RequesterId != nil {
+		conditions = append(conditions, "r.requester_id = "+arg(*filter.RequesterId))
+	}
+	if filter.PayerId != nil {
+		conditions = append(conditions, "r.payer_id = "+arg(*filter.PayerId))
+	}
+	switch filter.Status {
+	case "":
+	case entity.PaymentRequestPending:
+		conditions = append(conditions, "r.status = 'pending' AND r.expires_at > CURRENT_TIMESTAMP")
+	case entity.PaymentRequestExpired:
+		conditions = append(conditions, "r.status = 'pending' AND r.expires_at <= CURRENT_TIMESTAMP")
+	default:
+		conditions = append(conditions, "r.status = "+arg(filter.Status))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(r.created_at, r.id) < (%s, %s)", arg(filter.After.CreatedAt), arg(filter.After.Id)))
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "TRUE")
+	}
+
+	var requests []entity.PaymentRequest
+	query := `SELECT r.id, q.username AS requester, p.username AS payer, r.amount, r.message, 
+			  ` + paymentRequestStatus + ` AS status, r.expires_at, r.resolved_at, r.created_at 
+			  FROM payment_requests r 
+			  JOIN users q ON q.id = r.requester_id 
+			  JOIN users p ON p.id = r.payer_id 
+			  WHERE ` + strings.Join(conditions, " AND ") + ` 
+			  ORDER BY r.created_at DESC, r.id DESC 
+			  LIMIT ` + arg(filter.Limit+1)
+	err := postgres.Conn(ctx, t.db).Select(ctx, &requests, query, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list payment requests")
+	}
+
+	return requests, nil
+}
+
+// ApprovePaymentRequest pays a pending request addressed to payerID with the
+// same transfer SendCoin makes. The transfer is private, so it stays out of the
+// kudos feed.
+func (t Transaction) ApprovePaymentRequest(ctx context.Context, payerID uuid.UUID, requestID int64) (*entity.PaymentRequest, error) {
+	var request *entity.PaymentRequest
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		pending, err := lockPendingPaymentRequest(ctx, tx, payerID, requestID)
+		if err != nil {
+			return err
+		}
+
+		transferID, err := transferCoins(ctx, tx, payerID, entity.SendCoin{
+			ToUser:  pending.Requester,
+			Amount:  pending.Amount,
+			Message: pending.Message,
+			Private: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE payment_requests 
+				  SET status = 'approved', transfer_id = $2, resolved_at = CURRENT_TIMESTAMP 
+				  WHERE id = $1`
+		_, err = tx.Exec(ctx, query, requestID, transferID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to approve payment request")
+		}
+
+		request, err = getPaymentRequest(ctx, tx, requestID)
+		return err
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return request, nil
+}
+
+func (t Transaction) DeclinePaymentRequest(ctx context.Context, payerID uuid.UUID, requestID int64) (*entity.PaymentRequest, error) {
+	var request *entity.PaymentRequest
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		_, err := lockPendingPaymentRequest(ctx, tx, payerID, requestID)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE payment_requests 
+				  SET status = 'declined', resolved_at = CURRENT_TIMESTAMP 
+				  WHERE id = $1`
+		_, err = tx.Exec(ctx, query, requestID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to decline payment request")
+		}
+
+		request, err = getPaymentRequest(ctx, tx, requestID)
+		return err
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return request, nil
+}
+
+type pendingPaymentRequest struct {
+	Requester string
+	Amount    int
+	Message   string
+	Status    string
+	Expired   bool
+}
+
+// lockPendingPaymentRequest locks the request before the payer's account, so an
+// approval and a decline of the same request run one after the other.
+func lockPendingPaymentRequest(ctx context.Context, tx postgres.Tx, payerID uuid.UUID, requestID int64) (*pendingPaymentRequest, error) {
+	var requests []pendingPaymentRequest
+	query := `SELECT u.username AS requester, r.amount, r.message, r.status, 
+			  r.expires_at <= CURRENT_TIMESTAMP AS expired 
+			  FROM payment_requests r 
+			  JOIN users u ON u.id = r.requester_id 
+			  WHERE r.id = $1 AND r.payer_id = $2 
+			  FOR UPDATE OF r`
+	err := tx.Select(ctx, &requests, query, requestID, payerID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to lock payment request")
+	}
+
+	switch {
+	case len(requests) == 0:
+		return nil, domain.ErrPaymentRequestNotFound
+	case requests[0].Status != entity.PaymentRequestPending:
+		return nil, domain.ErrPaymentRequestResolved
+	case requests[0].Expired:
+		return nil, domain.ErrPaymentRequestExpired
+	}
+
+	return &requests[0], nil
+}
+
+func getPaymentRequest(ctx context.Context, tx postgres.Tx, requestID int64) (*entity.PaymentRequest, error) {
+	var request entity.PaymentRequest
+	query := `SELECT r.id, q.username AS requester, p.username AS payer, r.amount, r.message, 
+			  ` + paymentRequestStatus + ` AS status, r.expires_at, r.resolved_at, r.created_at 
+			  FROM payment_requests r 
+			  JOIN users q ON q.id = r.requester_id 
+			  JOIN users p ON p.id = r.payer_id 
+			  WHERE r.id = $1`
+	err := tx.Get(ctx, &request, query, requestID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get payment request")
+	}
+
+	return &request, nil
+}
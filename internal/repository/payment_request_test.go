@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_PaymentRequests(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ledger := NewLedger(db)
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, 100)
+	bob := createTestUser(t, db, 100)
+
+	_, err := repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: alice.Username, Amount: 10, TTL: time.Hour})
+	require.ErrorIs(t, err, domain.ErrSelfPaymentRequest)
+	_, err = repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: "ghost-" + bob.Username, Amount: 10, TTL: time.Hour})
+	require.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	lunch, err := repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: bob.Username, Amount: 30, Message: "lunch", TTL: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, entity.PaymentRequestPending, lunch.Status)
+	require.Equal(t, bob.Username, lunch.Payer)
+
+	taxi, err := repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: bob.Username, Amount: 20, TTL: time.Hour})
+	require.NoError(t, err)
+	huge, err := repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: bob.Username, Amount: 1000, TTL: time.Hour})
+	require.NoError(t, err)
+	stale, err := repo.CreatePaymentRequest(ctx, alice.Id, entity.NewPaymentRequest{Payer: bob.Username, Amount: 5, TTL: -time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, entity.PaymentRequestExpired, stale.Status)
+
+	// Only the addressee can resolve a request.
+	_, err = repo.ApprovePaymentRequest(ctx, alice.Id, lunch.Id)
+	require.ErrorIs(t, err, domain.ErrPaymentRequestNotFound)
+
+	incoming, err := repo.ListPaymentRequests(ctx, entity.PaymentRequestFilter{PayerId: &bob.Id, Status: entity.PaymentRequestPending, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, incoming, 3)
+
+	approved, err := repo.ApprovePaymentRequest(ctx, bob.Id, lunch.Id)
+	require.NoError(t, err)
+	require.Equal(t, entity.PaymentRequestApproved, approved.Status)
+	require.NotNil(t, approved.ResolvedAt)
+	require.EqualValues(t, 130, userCoins(t, db, alice.Id))
+	require.EqualValues(t, 70, userCoins(t, db, bob.Id))
+
+	_, err = repo.ApprovePaymentRequest(ctx, bob.Id, lunch.Id)
+	require.ErrorIs(t, err, domain.ErrPaymentRequestResolved)
+	_, err = repo.ApprovePaymentRequest(ctx, bob.Id, stale.Id)
+	require.ErrorIs(t, err, domain.ErrPaymentRequestExpired)
+
+	// A failed approval leaves the request pending.
+	_, err = repo.ApprovePaymentRequest(ctx, bob.Id, huge.Id)
+	require.ErrorIs(t, err, domain.ErrInsufficientFunds)
+
+	declined, err := repo.DeclinePaymentRequest(ctx, bob.Id, taxi.Id)
+	require.NoError(t, err)
+	require.Equal(t, entity.PaymentRequestDeclined, declined.Status)
+	require.EqualValues(t, 70, userCoins(t, db, bob.Id))
+
+	sent, err := repo.ListPaymentRequests(ctx, entity.PaymentRequestFilter{RequesterId: &alice.Id, Limit: 10})
+	require.NoError(t, err)
+	statuses := make([]string, 0, len(sent))
+	for _, request := range sent {
+		statuses = append(statuses, request.Status)
+	}
+	require.Equal(t, []string{
+		entity.PaymentRequestExpired, entity.PaymentRequestPending, entity.PaymentRequestDeclined, entity.PaymentRequestApproved,
+	}, statuses)
+
+	expired, err := repo.ListPaymentRequests(ctx, entity.PaymentRequestFilter{RequesterId: &alice.Id, Status: entity.PaymentRequestExpired, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	require.Equal(t, stale.Id, expired[0].Id)
+
+	// The approval is a private transfer, so it is in the history but not in the kudos feed.
+	history, err := repo.GetHistory(ctx, bob.Id, entity.HistoryFilter{Direction: domain.DirectionSent, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "lunch", history[0].Message)
+	require.True(t, history[0].Private)
+
+	// Declined and expired requests stay in the requester's history; the approved
+	// one is there as the transfer that paid it.
+	history, err = repo.GetHistory(ctx, alice.Id, entity.HistoryFilter{Direction: domain.DirectionRequest, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, entity.HistoryPaymentRequest, history[0].Kind)
+	require.Equal(t, stale.Id, history[0].Id)
+	require.Equal(t, entity.PaymentRequestExpired, history[0].Status)
+	require.Equal(t, taxi.Id, history[1].Id)
+	require.Equal(t, entity.PaymentRequestDeclined, history[1].Status)
+	require.Equal(t, bob.Username, history[1].FromUser)
+
+	history, err = repo.GetHistory(ctx, alice.Id, entity.HistoryFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	balance, err := ledger.Balance(ctx, bob.Id)
+	require.NoError(t, err)
+	require.Equal(t, userCoins(t, db, bob.Id), balance)
+}
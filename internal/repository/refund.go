@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"strconv"
+	"time"
+)
+
+// refundableLine is an order line locked for a refund. The order's status,
+// date and whether it was a gift are only loaded for returns.
+type refundableLine struct {
+	Id        int64
+	ItemType  string
+	Variant   string
+	Quantity  int
+	Returned  int
+	UnitPrice int64
+	Status    string
+	CreatedAt time.Time
+	Gift      bool
+}
+
+// ReturnItem takes the items back from the buyer's inventory, puts limited
+// stock back on the shelf and refunds the unit price paid for them.
+func (t Transaction) ReturnItem(
+	ctx context.Context,
+	userID uuid.UUID,
+	ret entity.ReturnItem,
+	idempotencyKey *entity.IdempotencyKey,
+) (*entity.Refund, *entity.IdempotencyKey, error) {
+	var (
+		refund *entity.Refund
+		replay *entity.IdempotencyKey
+	)
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		var err error
+		replay, err = claimIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil || replay != nil {
+			return err
+		}
+
+		// The order is locked before its line, in the order CancelOrder takes them, so
+		// a pickup or cancellation cannot commit between the status check and the refund.
+		query := `SELECT 1 FROM orders WHERE id = $1 AND user_id = $2 FOR SHARE`
+		_, err = tx.Exec(ctx, query, ret.OrderId, userID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to lock order")
+		}
+
+		// Locking the line serializes concurrent returns against its returned count.
+		var lines []refundableLine
+		query = `SELECT ol.id, ol.item_type, COALESCE(ol.variant, '') AS variant, ol.quantity, ol.returned, ol.unit_price, o.status, o.created_at, 
+				  EXISTS (SELECT 1 FROM gifts WHERE order_id = o.id) AS gift 
+				  FROM order_lines ol 
+				  JOIN orders o ON o.id = ol.order_id 
+				  WHERE o.id = $1 AND o.user_id = $2 AND ol.item_type = $3 AND ol.variant IS NOT DISTINCT FROM NULLIF($4, '') 
+				  FOR UPDATE OF ol`
+		err = tx.Select(ctx, &lines, query, ret.OrderId, userID, ret.ItemType, ret.Variant)
+		if err != nil {
+			return errors.WithMessage(err, "failed to lock order line")
+		}
+		if len(lines) == 0 {
+			return domain.ErrOrderNotFound
+		}
+
+		line := lines[0]
+		switch {
+		case line.Gift:
+			return domain.ErrGiftNotReturnable
+		case line.Status == entity.OrderCancelled:
+			return domain.ErrOrderCancelled
+		case line.Status == entity.OrderPickedUp:
+			return domain.ErrOrderPickedUp
+		case !line.CreatedAt.After(ret.PurchasedAfter):
+			return domain.ErrReturnWindowClosed
+		case line.Quantity-line.Returned < ret.Quantity:
+			return domain.ErrNotEnoughItems
+		}
+
+		err = putBackStock(ctx, tx, line.ItemType, line.Variant, ret.Quantity)
+		if err != nil {
+			return err
+		}
+
+		err = lockAccount(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		refund, err = refundOrderLine(ctx, tx, userID, userID, ret.OrderId, line, ret.Quantity)
+		if err != nil {
+			return err
+		}
+
+		return storeIdempotentResponse(ctx, tx, idempotencyKey, []byte(strconv.FormatInt(refund.Id, 10)))
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "transaction failed")
+	}
+
+	return refund, replay, nil
+}
+
+func (t Transaction) GetRefund(ctx context.Context, userID uuid.UUID, refundID int64) (*entity.Refund, error) {
+	var refunds []entity.Refund
+	query := `SELECT id, order_id, item_type, COALESCE(variant, '') AS variant, quantity, amount, created_at 
+			  FROM refunds 
+			  WHERE id = $1 AND user_id = $2`
+	err := postgres.Conn(ctx, t.db).Select(ctx, &refunds, query, refundID, userID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get refund")
+	}
+	if len(refunds) == 0 {
+		return nil, domain.ErrRefundNotFound
+	}
+
+	return &refunds[0], nil
+}
+
+// refundOrderLine takes quantity items of the line back from the holder's
+// inventory, which differs from the buyer's for gifts, and refunds the buyer.
+// Callers put the stock back and lock the buyer's row first, in the order
+// placeOrder takes the same locks, so a refund cannot deadlock against a purchase.
+func refundOrderLine(
+	ctx context.Context,
+	tx postgres.Tx,
+	buyerID uuid.UUID,
+	holderID uuid.UUID,
+	orderID int64,
+	line refundableLine,
+	quantity int,
+) (*entity.Refund, error) {
+	// Items given away or passed on since the purchase cannot be taken back.
+	err := takeUserItems(ctx, tx, holderID, line.ItemType, line.Variant, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE order_lines SET returned = returned + $2 WHERE id = $1`
+	_, err = tx.Exec(ctx, query, line.Id, quantity)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to update order line")
+	}
+
+	refund := entity.Refund{
+		OrderId:  orderID,
+		ItemType: line.ItemType,
+		Variant:  line.Variant,
+		Quantity: quantity,
+		Amount:   line.UnitPrice * int64(quantity),
+	}
+	query = `INSERT INTO refunds (order_id, user_id, item_type, variant, quantity, amount) 
+			 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6) 
+			 RETURNING id, created_at`
+	err = tx.Get(ctx, &refund, query, refund.OrderId, buyerID, refund.ItemType, refund.Variant, refund.Quantity, refund.Amount)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create refund")
+	}
+
+	// Free items were never paid for and move no coins back.
+	if refund.Amount > 0 {
+		entryID, err := postJournalEntry(ctx, tx, entity.JournalRefund, strconv.FormatInt(refund.Id, 10),
+			entity.Posting{AccountId: entity.RevenueAccountID, Amount: -refund.Amount},
+			entity.Posting{AccountId: buyerID, Amount: refund.Amount},
+		)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to record refund")
+		}
+
+		query = `UPDATE refunds SET journal_entry_id = $1 WHERE id = $2`
+		_, err = tx.Exec(ctx, query, entryID, refund.Id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to link refund to journal entry")
+		}
+
+		query = `UPDATE users 
+				 SET coin = coin + $1 
+				 WHERE id = $2`
+		_, err = tx.Exec(ctx, query, refund.Amount, buyerID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to update user coins")
+		}
+	}
+
+	return &refund, nil
+}
+
+// putBackStock returns items to the limited stock they were taken from: the
+// variant's own stock if it has one, otherwise the item's. Items in unlimited
+// supply are left alone.
+func putBackStock(ctx context.Context, tx postgres.Tx, itemType string, variant string, quantity int) error {
+	if variant != "" {
+		query := `UPDATE item_variants 
+				  SET stock = stock + $2 
+				  WHERE sku = $1 AND stock IS NOT NULL`
+		tag, err := tx.Exec(ctx, query, variant, quantity)
+		if err != nil {
+			return errors.WithMessage(err, "failed to put back variant stock")
+		}
+		if tag.RowsAffected() == 1 {
+			return nil
+		}
+	}
+
+	query := `UPDATE items 
+			  SET stock = stock + $2, updated_at = CURRENT_TIMESTAMP 
+			  WHERE type = $1 AND stock IS NOT NULL`
+	_, err := tx.Exec(ctx, query, itemType, quantity)
+	if err != nil {
+		return errors.WithMessage(err, "failed to put back stock")
+	}
+
+	return nil
+}
+
+func lockAccount(ctx context.Context, tx postgres.Tx, userID uuid.UUID) error {
+	query := `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`
+	_, err := tx.Exec(ctx, query, userID)
+	if err != nil {
+		return errors.WithMessage(err, "failed to lock account")
+	}
+
+	return nil
+}
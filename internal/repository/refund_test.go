@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_ReturnItem(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ledger := NewLedger(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db, 100)
+	friend := createTestUser(t, db, 100)
+	window := time.Now().Add(-time.Hour)
+
+	order, _, err := repo.Checkout(ctx, user.Id, []entity.OrderLine{{ItemType: "cup", Quantity: 2}, {ItemType: "pen", Quantity: 1}}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 50, userCoins(t, db, user.Id))
+
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "book", Quantity: 1, PurchasedAfter: window}, nil)
+	require.ErrorIs(t, err, domain.ErrOrderNotFound)
+	_, _, err = repo.ReturnItem(ctx, friend.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: window}, nil)
+	require.ErrorIs(t, err, domain.ErrOrderNotFound)
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: time.Now().Add(time.Hour)}, nil)
+	require.ErrorIs(t, err, domain.ErrReturnWindowClosed)
+
+	key := &entity.IdempotencyKey{UserId: user.Id, Key: uuid.NewString(), RequestHash: "hash", StatusCode: 200}
+	refund, replay, err := repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: window}, key)
+	require.NoError(t, err)
+	require.Nil(t, replay)
+	require.EqualValues(t, 20, refund.Amount)
+	require.EqualValues(t, 70, userCoins(t, db, user.Id))
+
+	_, replay, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: window}, key)
+	require.NoError(t, err)
+	require.Equal(t, strconv.FormatInt(refund.Id, 10), string(replay.Response))
+	require.EqualValues(t, 70, userCoins(t, db, user.Id))
+
+	// Only the rest of the line can still be returned.
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 2, PurchasedAfter: window}, nil)
+	require.ErrorIs(t, err, domain.ErrNotEnoughItems)
+
+	stored, err := repo.GetOrder(ctx, user.Id, order.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, stored.Lines[0].Returned)
+
+	info, err := repo.GetInfo(ctx, user.Id, 10)
+	require.NoError(t, err)
+	require.Len(t, info.Refunds, 1)
+	require.Equal(t, order.Id, info.Refunds[0].OrderId)
+	require.Contains(t, info.Inventory, entity.Item{Type: "cup", Quantity: 1})
+
+	history, err := repo.GetHistory(ctx, user.Id, entity.HistoryFilter{Direction: domain.DirectionRefund, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, entity.HistoryRefund, history[0].Kind)
+	require.Equal(t, refund.Id, history[0].Id)
+	require.Equal(t, order.Id, history[0].OrderId)
+	require.Equal(t, 20, history[0].Amount)
+
+	_, err = repo.GetRefund(ctx, friend.Id, refund.Id)
+	require.ErrorIs(t, err, domain.ErrRefundNotFound)
+
+	balance, err := ledger.Balance(ctx, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, userCoins(t, db, user.Id), balance)
+
+	_, err = db.Exec(ctx, `UPDATE orders SET status = 'picked_up', picked_up_at = CURRENT_TIMESTAMP WHERE id = $1`, order.Id)
+	require.NoError(t, err)
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "pen", Quantity: 1, PurchasedAfter: window}, nil)
+	require.ErrorIs(t, err, domain.ErrOrderPickedUp)
+
+	gift, _, err := repo.SendGift(ctx, user.Id, entity.SendGift{ToUser: friend.Username, ItemType: "pen", Quantity: 1, Source: entity.GiftSourcePurchase}, nil)
+	require.NoError(t, err)
+	_, _, err = repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: gift.Id, ItemType: "pen", Quantity: 1, PurchasedAfter: window}, nil)
+	require.ErrorIs(t, err, domain.ErrGiftNotReturnable)
+}
+
+func TestTransaction_ReturnItem_ConcurrentPickup(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewTransaction(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db, 100)
+	admin := createTestUser(t, db, entity.Coin)
+
+	order, _, err := repo.BuyItem(ctx, user.Id, "cup", "", nil)
+	require.NoError(t, err)
+	_, err = repo.SetOrderStatus(ctx, order.Id, entity.OrderCreated, entity.OrderReady, admin.Id)
+	require.NoError(t, err)
+
+	// Hold the pickup open so the return has to wait for it.
+	pickup, err := db.Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = pickup.Rollback(ctx) }()
+	_, err = pickup.Exec(ctx, `UPDATE orders SET status = 'picked_up', picked_up_at = CURRENT_TIMESTAMP WHERE id = $1`, order.Id)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := repo.ReturnItem(ctx, user.Id, entity.ReturnItem{OrderId: order.Id, ItemType: "cup", Quantity: 1, PurchasedAfter: time.Now().Add(-time.Hour)}, nil)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		t.Fatalf("return finished before the pickup committed: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, pickup.Commit(ctx))
+	require.ErrorIs(t, <-done, domain.ErrOrderPickedUp)
+	require.EqualValues(t, 80, userCoins(t, db, user.Id))
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"avito_test/pkg/storage/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"time"
+)
+
+type Token struct {
+	db postgres.Postgres
+}
+
+func NewToken(db postgres.Postgres) Token {
+	return Token{
+		db: db,
+	}
+}
+
+func (t Token) CreateRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) 
+			  VALUES ($1, $2, $3, $4, $5)`
+	_, err := postgres.Conn(ctx, t.db).Exec(ctx, query, token.Id, token.UserId, token.FamilyId, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create refresh token")
+	}
+
+	return nil
+}
+
+func (t Token) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+
+	query := `SELECT rt.id, rt.user_id, a.username, rt.family_id, rt.token_hash, rt.expires_at, rt.revoked_at, rt.created_at 
+			  FROM refresh_tokens rt 
+			  JOIN auth a ON a.id = rt.user_id 
+			  WHERE rt.token_hash = $1`
+	err := postgres.Conn(ctx, t.db).Get(ctx, &token, query, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get refresh token")
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken revokes the old token and stores its replacement in the
+// same family. It fails with domain.ErrRefreshTokenReused when the old token
+// has already been rotated by a concurrent request.
+func (t Token) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, token entity.RefreshToken) error {
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) 
+				  VALUES ($1, $2, $3, $4, $5)`
+		_, err := tx.Exec(ctx, query, token.Id, token.UserId, token.FamilyId, token.TokenHash, token.ExpiresAt)
+		if err != nil {
+			return errors.WithMessage(err, "failed to create refresh token")
+		}
+
+		query = `UPDATE refresh_tokens 
+				 SET revoked_at = now(), replaced_by = $1 
+				 WHERE id = $2 AND revoked_at IS NULL`
+		tag, err := tx.Exec(ctx, query, token.Id, oldID)
+		if err != nil {
+			return errors.WithMessage(err, "failed to revoke refresh token")
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrRefreshTokenReused
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "transaction failed")
+	}
+
+	return nil
+}
+
+func (t Token) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := postgres.Conn(ctx, t.db).Exec(ctx, query, familyID)
+	if err != nil {
+		return errors.WithMessage(err, "failed to revoke refresh token family")
+	}
+
+	return nil
+}
+
+func (t Token) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_access_tokens (jti, expires_at) 
+			  VALUES ($1, $2) 
+			  ON CONFLICT (jti) DO NOTHING`
+	_, err := postgres.Conn(ctx, t.db).Exec(ctx, query, jti, expiresAt)
+	if err != nil {
+		return errors.WithMessage(err, "failed to revoke access token")
+	}
+
+	return nil
+}
+
+// GetRevokedAccessToken returns nil when the token has not been revoked or its
+// revocation has already expired.
+func (t Token) GetRevokedAccessToken(ctx context.Context, jti string) (*entity.RevokedToken, error) {
+	var tokens []entity.RevokedToken
+
+	query := `SELECT jti, expires_at FROM revoked_access_tokens WHERE jti = $1 AND expires_at > now()`
+	err := postgres.Conn(ctx, t.db).Select(ctx, &tokens, query, jti)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get revoked access token")
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	return &tokens[0], nil
+}
+
+func (t Token) DeleteExpiredAccessTokens(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM revoked_access_tokens WHERE expires_at < $1`
+	tag, err := postgres.Conn(ctx, t.db).Exec(ctx, query, before)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to delete expired revoked access tokens")
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -1,12 +1,33 @@
 package repository
 
 import (
+	"avito_test/internal/domain"
 	"avito_test/internal/entity"
 	"avito_test/pkg/storage/postgres"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	"time"
+)
+
+var (
+	// moneyTxOptions stay on READ COMMITTED: money transactions lock the rows they decide on,
+	// so a lock wait re-reads the latest version instead of failing with a serialization error.
+	moneyTxOptions = []postgres.TxOption{
+		postgres.WithIsolation(pgx.ReadCommitted),
+		postgres.WithStatementTimeout(5 * time.Second),
+	}
+
+	// snapshotTxOptions give multi-query reads a single consistent view of the data.
+	snapshotTxOptions = []postgres.TxOption{
+		postgres.WithIsolation(pgx.RepeatableRead),
+		postgres.ReadOnly(),
+	}
 )
 
 type Transaction struct {
@@ -19,36 +40,98 @@ func NewTransaction(db postgres.Postgres) Transaction {
 	}
 }
 
-func (t Transaction) GetInfo(ctx context.Context, userID uuid.UUID) (*entity.Info, error) {
+// GetInfo embeds at most historyLimit of the most recent received transfers, sent
+// transfers, purchases, sent gifts, received gifts and refunds each.
+func (t Transaction) GetInfo(ctx context.Context, userID uuid.UUID, historyLimit int) (*entity.Info, error) {
 	var info entity.Info
 
 	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		info = entity.Info{}
+
 		query := `SELECT coin FROM users WHERE id = $1`
 		err := tx.Get(ctx, &info.Coins, query, userID)
 		if err != nil {
 			return errors.WithMessage(err, "failed to get user coins")
 		}
 
-		query = `SELECT type, quantity FROM user_items WHERE user_id = $1`
+		query = `SELECT type, COALESCE(variant, '') AS variant, quantity FROM user_items WHERE user_id = $1`
 		err = tx.Select(ctx, &info.Inventory, query, userID)
 		if err != nil {
 			return errors.WithMessage(err, "failed to get user inventory")
 		}
 
-		query = `SELECT from_user, amount FROM coin_transactions WHERE to_user = (SELECT username FROM users WHERE id = $1)`
-		err = tx.Select(ctx, &info.CoinHistory.Received, query, userID)
+		query = `SELECT from_user, amount 
+				 FROM coin_transactions 
+				 WHERE to_user = (SELECT username FROM users WHERE id = $1) 
+				 ORDER BY created_at DESC, id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.CoinHistory.Received, query, userID, historyLimit)
 		if err != nil {
 			return errors.WithMessage(err, "failed to get received transactions")
 		}
 
-		query = `SELECT to_user, amount FROM coin_transactions WHERE from_user = (SELECT username FROM users WHERE id = $1)`
-		err = tx.Select(ctx, &info.CoinHistory.Sent, query, userID)
+		query = `SELECT to_user, amount 
+				 FROM coin_transactions 
+				 WHERE from_user = (SELECT username FROM users WHERE id = $1) 
+				 ORDER BY created_at DESC, id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.CoinHistory.Sent, query, userID, historyLimit)
 		if err != nil {
 			return errors.WithMessage(err, "failed to get sent transactions")
 		}
 
+		query = `SELECT id, user_id, total, status, created_at 
+				 FROM orders 
+				 WHERE user_id = $1 
+				 ORDER BY created_at DESC, id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.RecentPurchases, query, userID, historyLimit)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get recent purchases")
+		}
+
+		if err = loadOrderLines(ctx, tx, info.RecentPurchases); err != nil {
+			return err
+		}
+
+		query = `SELECT g.id, f.username AS from_user, r.username AS to_user, g.item_type, COALESCE(g.variant, '') AS variant, 
+				 g.quantity, g.source, g.order_id, g.message, g.created_at 
+				 FROM gifts g 
+				 JOIN users f ON f.id = g.from_user 
+				 JOIN users r ON r.id = g.to_user 
+				 WHERE g.from_user = $1 
+				 ORDER BY g.created_at DESC, g.id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.Gifted, query, userID, historyLimit)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get sent gifts")
+		}
+
+		query = `SELECT g.id, f.username AS from_user, r.username AS to_user, g.item_type, COALESCE(g.variant, '') AS variant, 
+				 g.quantity, g.source, g.order_id, g.message, g.created_at 
+				 FROM gifts g 
+				 JOIN users f ON f.id = g.from_user 
+				 JOIN users r ON r.id = g.to_user 
+				 WHERE g.to_user = $1 
+				 ORDER BY g.created_at DESC, g.id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.ReceivedGifts, query, userID, historyLimit)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get received gifts")
+		}
+
+		query = `SELECT id, order_id, item_type, COALESCE(variant, '') AS variant, quantity, amount, created_at 
+				 FROM refunds 
+				 WHERE user_id = $1 
+				 ORDER BY created_at DESC, id DESC 
+				 LIMIT $2`
+		err = tx.Select(ctx, &info.Refunds, query, userID, historyLimit)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get refunds")
+		}
+
 		return nil
-	})
+	}, snapshotTxOptions...)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "transaction failed")
@@ -57,98 +140,229 @@ func (t Transaction) GetInfo(ctx context.Context, userID uuid.UUID) (*entity.Inf
 	return &info, nil
 }
 
-func (t Transaction) BuyItem(ctx context.Context, userID uuid.UUID, itemType string) error {
+// GetHistory returns one page of the user's transfers and refunds, newest first.
+// It fetches up to filter.Limit+1 rows so the caller can tell whether another
+// page exists.
+func (t Transaction) GetHistory(ctx context.Context, userID uuid.UUID, filter entity.HistoryFilter) ([]entity.HistoryEntry, error) {
+	var entries []entity.HistoryEntry
+
 	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
-		var price int64
-		query := `SELECT price 
-				  FROM items 
-				  WHERE type = $1 
-				  LIMIT 1`
-		err := tx.Get(ctx, &price, query, itemType)
+		var username string
+		err := tx.Get(ctx, &username, `SELECT username FROM users WHERE id = $1`, userID)
 		if err != nil {
-			return errors.WithMessage(err, "failed to get item price")
+			return errors.WithMessage(err, "failed to get username")
 		}
 
-		var user entity.User
-		query = `SELECT id, username, coin 
-				 FROM users 
-				 WHERE id = $1`
-		err = tx.Get(ctx, &user, query, userID)
-		if err != nil {
-			return errors.WithMessage(err, "failed to get user by ID")
+		args := []any{username, userID}
+		arg := func(value any) string {
+			args = append(args, value)
+			return fmt.Sprintf("$%d", len(args))
 		}
 
-		newCoinBalance := user.Coin - price
-		query = `UPDATE users 
-				 SET coin = $1 
-				 WHERE id = $2 
-				 RETURNING coin`
-		err = tx.Get(ctx, &newCoinBalance, query, newCoinBalance, userID)
-		if err != nil {
-			return errors.WithMessage(err, "failed to update user coins")
+		var conditions []string
+		if filter.Direction != "" {
+			conditions = append(conditions, "direction = "+arg(filter.Direction))
+		}
+		// Refunds have no counterparty, so this filter leaves only transfers and payment requests.
+		if filter.Counterparty != "" {
+			conditions = append(conditions, "CASE WHEN from_user = $1 THEN to_user ELSE from_user END = "+arg(filter.Counterparty))
+		}
+		if filter.MinAmount > 0 {
+			conditions = append(conditions, "amount >= "+arg(filter.MinAmount))
+		}
+		if filter.MaxAmount > 0 {
+			conditions = append(conditions, "amount <= "+arg(filter.MaxAmount))
+		}
+		if !filter.From.IsZero() {
+			conditions = append(conditions, "created_at >= "+arg(filter.From))
+		}
+		if !filter.To.IsZero() {
+			conditions = append(conditions, "created_at < "+arg(filter.To))
+		}
+		if filter.After != nil {
+			// Cursors issued before refunds were listed always point at a transfer.
+			kind := filter.After.Kind
+			if kind == "" {
+				kind = entity.HistoryTransfer
+			}
+			conditions = append(conditions, fmt.Sprintf("(created_at, kind, id) < (%s, %s, %s)",
+				arg(filter.After.CreatedAt), arg(kind), arg(filter.After.Id)))
 		}
 
-		query = `INSERT INTO user_items (user_id, type, quantity) 
-				  VALUES ($1, $2, 1) 
-				  ON CONFLICT (user_id, type) 
-				  DO UPDATE SET quantity = user_items.quantity + 1`
-		_, err = tx.Exec(ctx, query, userID, itemType)
+		where := ""
+		if len(conditions) > 0 {
+			where = "WHERE " + strings.Join(conditions, " AND ")
+		}
+
+		// Approved payment requests are already listed as the transfer that paid them.
+		query := `SELECT id, kind, direction, from_user, to_user, amount, message, category, private, order_id, status, created_at 
+				  FROM (
+					  SELECT id, 'transfer' AS kind, CASE WHEN from_user = $1 THEN 'sent' ELSE 'received' END AS direction, 
+					  COALESCE(from_user, '') AS from_user, COALESCE(to_user, '') AS to_user, amount, 
+					  message, COALESCE(category, '') AS category, private, 0::BIGINT AS order_id, '' AS status, created_at 
+					  FROM coin_transactions 
+					  WHERE from_user = $1 OR to_user = $1 
+					  UNION ALL 
+					  SELECT id, 'refund', 'refund', '', $1::TEXT, amount, '', '', false, order_id, '', created_at 
+					  FROM refunds 
+					  WHERE user_id = $2 
+					  UNION ALL 
+					  SELECT r.id, 'payment_request', 'request', p.username, $1::TEXT, r.amount, r.message, '', false, 0, 
+					  ` + paymentRequestStatus + `, r.created_at 
+					  FROM payment_requests r 
+					  JOIN users p ON p.id = r.payer_id 
+					  WHERE r.requester_id = $2 
+					  AND (r.status = 'declined' OR (r.status = 'pending' AND r.expires_at <= CURRENT_TIMESTAMP))
+				  ) h 
+				  ` + where + ` 
+				  ORDER BY created_at DESC, kind DESC, id DESC 
+				  LIMIT ` + arg(filter.Limit+1)
+		err = tx.Select(ctx, &entries, query, args...)
 		if err != nil {
-			return errors.WithMessage(err, "failed to add user item")
+			return errors.WithMessage(err, "failed to get coin history")
 		}
 
 		return nil
-	})
+	}, snapshotTxOptions...)
 
 	if err != nil {
-		return errors.Wrap(err, "transaction failed")
+		return nil, errors.Wrap(err, "transaction failed")
 	}
 
-	return nil
+	return entries, nil
 }
 
-func (t Transaction) SendCoin(ctx context.Context, userID uuid.UUID, send entity.SendCoin) error {
+// BuyItem places a single-item order; variant is empty for items without
+// variants. On replay the order is nil.
+func (t Transaction) BuyItem(
+	ctx context.Context,
+	userID uuid.UUID,
+	itemType string,
+	variant string,
+	idempotencyKey *entity.IdempotencyKey,
+) (*entity.Order, *entity.IdempotencyKey, error) {
+	var (
+		order  *entity.Order
+		replay *entity.IdempotencyKey
+	)
+
 	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
-		var sender struct {
-			Username string
-			Coin     int
+		var err error
+		replay, err = claimIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil || replay != nil {
+			return err
 		}
 
-		query := `SELECT username, coin FROM users WHERE id = $1 FOR UPDATE`
-		err := tx.Get(ctx, &sender, query, userID)
-		if err != nil {
-			return errors.WithMessage(err, "failed to get sender")
-		}
+		order, err = placeOrder(ctx, tx, userID, userID, []entity.OrderLine{{ItemType: itemType, Variant: variant, Quantity: 1}})
+		return err
+	}, moneyTxOptions...)
 
-		if sender.Coin < send.Amount {
-			return errors.New("insufficient balance")
-		}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "transaction failed")
+	}
 
-		newSenderBalance := sender.Coin - send.Amount
-		query = `UPDATE users SET coin = $1 WHERE id = $2 RETURNING coin`
-		err = tx.Get(ctx, &newSenderBalance, query, newSenderBalance, userID)
-		if err != nil {
-			return errors.WithMessage(err, "failed to update sender balance")
-		}
+	return order, replay, nil
+}
 
-		query = `UPDATE users SET coin = coin + $1 WHERE username = $2 RETURNING coin`
-		var newReceiverBalance int
-		err = tx.Get(ctx, &newReceiverBalance, query, send.Amount, send.ToUser)
-		if err != nil {
-			return errors.WithMessage(err, "failed to update receiver balance")
+type lockedAccount struct {
+	Id       uuid.UUID
+	Username string
+	Coin     int
+}
+
+func (t Transaction) SendCoin(
+	ctx context.Context,
+	userID uuid.UUID,
+	send entity.SendCoin,
+	idempotencyKey *entity.IdempotencyKey,
+) (*entity.IdempotencyKey, error) {
+	var replay *entity.IdempotencyKey
+
+	err := postgres.ExecTx(ctx, t.db, func(tx postgres.Tx) error {
+		var err error
+		replay, err = claimIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil || replay != nil {
+			return err
 		}
 
-		query = `INSERT INTO coin_transactions (from_user, to_user, amount) VALUES ($1, $2, $3)`
-		_, err = tx.Exec(ctx, query, sender.Username, send.ToUser, send.Amount)
-		if err != nil {
-			return errors.WithMessage(err, "failed to insert coin transaction")
+		_, err = transferCoins(ctx, tx, userID, send)
+		return err
+	}, moneyTxOptions...)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "transaction failed")
+	}
+	return replay, nil
+}
+
+// transferCoins moves coins between two accounts and records the transfer in
+// coin_transactions and the journal. It returns the coin_transactions id.
+func transferCoins(ctx context.Context, tx postgres.Tx, userID uuid.UUID, send entity.SendCoin) (int64, error) {
+	// Lock sender and recipient in id order so opposite transfers cannot deadlock.
+	var accounts []lockedAccount
+	query := `SELECT id, username, coin 
+			  FROM users 
+			  WHERE id = $1 OR username = $2 
+			  ORDER BY id 
+			  FOR UPDATE`
+	err := tx.Select(ctx, &accounts, query, userID, send.ToUser)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to lock accounts")
+	}
+
+	var sender, recipient *lockedAccount
+	for i := range accounts {
+		if accounts[i].Id == userID {
+			sender = &accounts[i]
+		}
+		if accounts[i].Username == send.ToUser {
+			recipient = &accounts[i]
 		}
+	}
+	if sender == nil {
+		return 0, errors.New("failed to get sender")
+	}
 
-		return nil
-	})
+	if sender.Username == send.ToUser {
+		return 0, domain.ErrSelfTransfer
+	}
+
+	if recipient == nil {
+		return 0, domain.ErrRecipientNotFound
+	}
+
+	if sender.Coin < send.Amount {
+		return 0, domain.ErrInsufficientFunds
+	}
+
+	query = `UPDATE users SET coin = coin - $1 WHERE id = $2`
+	_, err = tx.Exec(ctx, query, send.Amount, userID)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to update sender balance")
+	}
+
+	query = `UPDATE users SET coin = coin + $1 WHERE id = $2`
+	_, err = tx.Exec(ctx, query, send.Amount, recipient.Id)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to update receiver balance")
+	}
 
+	var transferID int64
+	query = `INSERT INTO coin_transactions (from_user, to_user, amount, message, category, private) 
+			 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6) 
+			 RETURNING id`
+	err = tx.Get(ctx, &transferID, query, sender.Username, send.ToUser, send.Amount, send.Message, send.Category, send.Private)
 	if err != nil {
-		return errors.Wrap(err, "transaction failed")
+		return 0, errors.WithMessage(err, "failed to insert coin transaction")
 	}
-	return nil
+
+	_, err = postJournalEntry(ctx, tx, entity.JournalTransfer, strconv.FormatInt(transferID, 10),
+		entity.Posting{AccountId: sender.Id, Amount: -int64(send.Amount)},
+		entity.Posting{AccountId: recipient.Id, Amount: int64(send.Amount)},
+	)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to record transfer")
+	}
+
+	return transferID, nil
 }
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"avito_test/pkg/storage/postgres"
+	"context"
+)
+
+// TxManager composes repository calls into one money-moving transaction.
+type TxManager struct {
+	manager postgres.TxManager
+}
+
+func NewTxManager(db postgres.Postgres) TxManager {
+	return TxManager{
+		manager: postgres.NewTxManager(db),
+	}
+}
+
+// Do runs fn in a transaction started with the options the money-moving methods use on their own.
+func (m TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.manager.Do(ctx, fn, moneyTxOptions...)
+}
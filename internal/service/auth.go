@@ -4,48 +4,326 @@ import (
 	"avito_test/internal/domain"
 	"avito_test/internal/entity"
 	"avito_test/internal/jwt"
+	"avito_test/pkg/logger"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"time"
 )
 
+const refreshTokenLength = 32
+
 type AuthRepository interface {
-	Auth(ctx context.Context, user entity.Auth) (*entity.Auth, error)
+	GetByUsername(ctx context.Context, username string) (*entity.Auth, error)
+	Create(ctx context.Context, user entity.Auth) (*entity.Auth, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	GetRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token entity.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID uuid.UUID, token entity.RefreshToken) error
+	RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+type RevocationCache interface {
+	Set(jti string, expiresAt time.Time)
+}
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	NeedsRehash(encoded string) bool
 }
 
 type Auth struct {
-	repo AuthRepository
-	jwt  *jwt.Service
+	repo       AuthRepository
+	tokens     TokenRepository
+	revoked    RevocationCache
+	jwt        *jwt.Service
+	hasher     PasswordHasher
+	refreshTTL time.Duration
+	logger     *logger.ApiLogger
 }
 
-func NewAuth(repo AuthRepository, jwtService *jwt.Service) Auth {
+func NewAuth(
+	repo AuthRepository,
+	tokens TokenRepository,
+	revoked RevocationCache,
+	jwtService *jwt.Service,
+	hasher PasswordHasher,
+	refreshTTL time.Duration,
+	logger *logger.ApiLogger,
+) Auth {
 	return Auth{
-		repo: repo,
-		jwt:  jwtService,
+		repo:       repo,
+		tokens:     tokens,
+		revoked:    revoked,
+		jwt:        jwtService,
+		hasher:     hasher,
+		refreshTTL: refreshTTL,
+		logger:     logger,
 	}
 }
 
+// Auth logs an existing user in or registers them on first sight.
 func (a Auth) Auth(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error) {
-	entityAuth := entity.Auth{
-		Username: req.Username,
-		Password: req.Password,
+	if !validateAuthRequest(req) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	authUser, err := a.repo.GetByUsername(ctx, req.Username)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		authUser, err = a.createUser(ctx, req)
+		if errors.Is(err, domain.ErrUserAlreadyExists) {
+			// a concurrent request has registered the same username first
+			return a.Login(ctx, req)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "create user failed")
+		}
+
+		return a.issueTokens(ctx, authUser.Id, authUser.Username, authUser.Roles)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get user failed")
+	}
+
+	if err = a.checkPassword(ctx, authUser, req.Password); err != nil {
+		return nil, err
 	}
 
-	authUser, err := a.repo.Auth(ctx, entityAuth)
+	return a.issueTokens(ctx, authUser.Id, authUser.Username, authUser.Roles)
+}
+
+func (a Auth) Register(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error) {
+	if !validateAuthRequest(req) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	authUser, err := a.createUser(ctx, req)
+	if errors.Is(err, domain.ErrUserAlreadyExists) {
+		return nil, domain.ErrUserAlreadyExists
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "create user failed")
 	}
 
+	return a.issueTokens(ctx, authUser.Id, authUser.Username, authUser.Roles)
+}
+
+func (a Auth) Login(ctx context.Context, req domain.AuthRequest) (*domain.AuthResponse, error) {
+	if !validateAuthRequest(req) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	authUser, err := a.repo.GetByUsername(ctx, req.Username)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get user failed")
+	}
+
+	if err = a.checkPassword(ctx, authUser, req.Password); err != nil {
+		return nil, err
+	}
+
+	return a.issueTokens(ctx, authUser.Id, authUser.Username, authUser.Roles)
+}
+
+func (a Auth) createUser(ctx context.Context, req domain.AuthRequest) (*entity.Auth, error) {
+	passwordHash, err := a.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, errors.Wrap(err, "hash password failed")
+	}
+
+	return a.repo.Create(ctx, entity.Auth{
+		Username: req.Username,
+		Password: passwordHash,
+	})
+}
+
+// checkPassword verifies the password against the stored hash and upgrades
+// the hash when it was made with an outdated algorithm or parameters. The
+// upgrade is best effort: when it fails the login still succeeds and the hash
+// is upgraded on a later login.
+func (a Auth) checkPassword(ctx context.Context, authUser *entity.Auth, password string) error {
+	ok, err := a.hasher.Verify(authUser.Password, password)
+	if err != nil {
+		return errors.Wrap(err, "verify password failed")
+	}
+	if !ok {
+		return domain.ErrInvalidCredentials
+	}
+
+	if !a.hasher.NeedsRehash(authUser.Password) {
+		return nil
+	}
+
+	passwordHash, err := a.hasher.Hash(password)
+	if err != nil {
+		a.logger.Errorf("failed to rehash password of %s: %v", authUser.Username, err)
+		return nil
+	}
+
+	if err = a.repo.UpdatePassword(ctx, authUser.Id, passwordHash); err != nil {
+		a.logger.Errorf("failed to update password hash of %s: %v", authUser.Username, err)
+	}
+
+	return nil
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// Presenting a token that has already been rotated revokes its whole family.
+func (a Auth) Refresh(ctx context.Context, req domain.RefreshRequest) (*domain.AuthResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, domain.ErrInvalidInput
+	}
+
+	stored, err := a.tokens.GetRefreshTokenByHash(ctx, hashRefreshToken(req.RefreshToken))
+	if errors.Is(err, domain.ErrInvalidRefreshToken) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "get refresh token failed")
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, a.revokeFamily(ctx, stored.FamilyId)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	refreshToken, next, err := a.newRefreshToken(stored.UserId, stored.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.tokens.RotateRefreshToken(ctx, stored.Id, next)
+	if errors.Is(err, domain.ErrRefreshTokenReused) {
+		return nil, a.revokeFamily(ctx, stored.FamilyId)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "rotate refresh token failed")
+	}
+
+	roles, err := a.repo.GetRoles(ctx, stored.UserId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user roles failed")
+	}
+
+	return a.accessToken(stored.UserId, stored.Username, roles, refreshToken)
+}
+
+// Logout revokes the presented access token and, when given, the family of
+// the refresh token issued alongside it.
+func (a Auth) Logout(ctx context.Context, userIDStr string, claims jwt.Claims, req domain.LogoutRequest) error {
+	if !validateUUID(userIDStr) {
+		return domain.ErrInvalidCredentials
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	if claims.TokenID != "" {
+		if err := a.tokens.RevokeAccessToken(ctx, claims.TokenID, claims.ExpiresAt); err != nil {
+			return errors.Wrap(err, "revoke access token failed")
+		}
+		a.revoked.Set(claims.TokenID, claims.ExpiresAt)
+	}
+
+	if req.RefreshToken == "" {
+		return nil
+	}
+
+	stored, err := a.tokens.GetRefreshTokenByHash(ctx, hashRefreshToken(req.RefreshToken))
+	if errors.Is(err, domain.ErrInvalidRefreshToken) {
+		return domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return errors.Wrap(err, "get refresh token failed")
+	}
+
+	if stored.UserId != userID {
+		return domain.ErrInvalidRefreshToken
+	}
+
+	if err = a.tokens.RevokeTokenFamily(ctx, stored.FamilyId); err != nil {
+		return errors.Wrap(err, "revoke refresh token family failed")
+	}
+
+	return nil
+}
+
+func (a Auth) issueTokens(ctx context.Context, userID uuid.UUID, username string, roles []string) (*domain.AuthResponse, error) {
+	refreshToken, stored, err := a.newRefreshToken(userID, uuid.New())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.tokens.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, errors.Wrap(err, "create refresh token failed")
+	}
+
+	return a.accessToken(userID, username, roles, refreshToken)
+}
+
+func (a Auth) accessToken(userID uuid.UUID, username string, roles []string, refreshToken string) (*domain.AuthResponse, error) {
 	token, err := a.jwt.GenerateJWT(jwt.Claims{
-		ID:       authUser.Id.String(),
-		Username: authUser.Username,
+		ID:       userID.String(),
+		Username: username,
+		Roles:    roles,
 	})
 	if err != nil {
 		return nil, domain.ErrUnauthorized
 	}
 
 	res := domain.AuthResponse{
-		Token: token,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(a.jwt.TTL().Seconds()),
 	}
 
 	return &res, nil
 }
+
+func (a Auth) newRefreshToken(userID, familyID uuid.UUID) (string, entity.RefreshToken, error) {
+	raw := make([]byte, refreshTokenLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", entity.RefreshToken{}, errors.Wrap(err, "generate refresh token failed")
+	}
+
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	return refreshToken, entity.RefreshToken{
+		Id:        uuid.New(),
+		UserId:    userID,
+		FamilyId:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(a.refreshTTL),
+	}, nil
+}
+
+func (a Auth) revokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if err := a.tokens.RevokeTokenFamily(ctx, familyID); err != nil {
+		return errors.Wrap(err, "revoke refresh token family failed")
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateAuthRequest(req domain.AuthRequest) bool {
+	return req.Username != "" && req.Password != ""
+}
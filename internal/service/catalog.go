@@ -0,0 +1,318 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"regexp"
+	"unicode/utf8"
+)
+
+type CatalogRepository interface {
+	ListItems(ctx context.Context) ([]entity.CatalogItem, error)
+	GetItem(ctx context.Context, itemType string) (*entity.CatalogItem, error)
+	CreateItem(ctx context.Context, item entity.CatalogItem, changedBy uuid.UUID) (*entity.CatalogItem, error)
+	UpdateItem(ctx context.Context, itemType string, update entity.ItemUpdate, changedBy uuid.UUID) (*entity.CatalogItem, error)
+	RetireItem(ctx context.Context, itemType string, changedBy uuid.UUID) (*entity.CatalogItem, error)
+	RestockItem(ctx context.Context, itemType string, sku string, quantity int, changedBy uuid.UUID) (*entity.CatalogItem, error)
+	CreateVariant(ctx context.Context, itemType string, variant entity.ItemVariant, changedBy uuid.UUID) (*entity.CatalogItem, error)
+	GetPriceHistory(ctx context.Context, itemType string) ([]entity.PriceChange, error)
+}
+
+const (
+	maxItemPrice       = 1_000_000
+	maxItemStock       = 1_000_000
+	maxItemDescription = 500
+	maxVariantLabel    = 32
+	defaultCategory    = "other"
+)
+
+var (
+	categoryRe = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	skuRe      = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+type Catalog struct {
+	repo CatalogRepository
+}
+
+func NewCatalog(repo CatalogRepository) Catalog {
+	return Catalog{
+		repo: repo,
+	}
+}
+
+func (c Catalog) List(ctx context.Context) (*domain.CatalogResponse, error) {
+	items, err := c.repo.ListItems(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list catalog")
+	}
+
+	res := domain.CatalogResponse{
+		Items: make([]domain.CatalogItem, 0, len(items)),
+	}
+	for _, item := range items {
+		res.Items = append(res.Items, catalogItemResponse(item))
+	}
+
+	return &res, nil
+}
+
+func (c Catalog) Get(ctx context.Context, itemType string) (*domain.CatalogItem, error) {
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	item, err := c.repo.GetItem(ctx, itemType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get catalog item")
+	}
+
+	res := catalogItemResponse(*item)
+	return &res, nil
+}
+
+func (c Catalog) Create(ctx context.Context, adminIDStr string, req domain.CreateItemRequest) (*domain.CatalogItem, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	item := entity.CatalogItem{
+		Type:              req.Type,
+		Price:             req.Price,
+		Description:       req.Description,
+		Category:          req.Category,
+		Available:         true,
+		Stock:             req.Stock,
+		LowStockThreshold: req.LowStockThreshold,
+	}
+	if item.Category == "" {
+		item.Category = defaultCategory
+	}
+	if req.Available != nil {
+		item.Available = *req.Available
+	}
+
+	if !validateItemType(item.Type) || !validPrice(item.Price) ||
+		!validDescription(item.Description) || !categoryRe.MatchString(item.Category) ||
+		(item.Stock != nil && !validStock(*item.Stock)) ||
+		(item.LowStockThreshold != nil && !validStock(*item.LowStockThreshold)) {
+		return nil, domain.ErrInvalidItem
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	created, err := c.repo.CreateItem(ctx, item, adminID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create catalog item")
+	}
+
+	res := catalogItemResponse(*created)
+	return &res, nil
+}
+
+func (c Catalog) Update(ctx context.Context, adminIDStr string, itemType string, req domain.UpdateItemRequest) (*domain.CatalogItem, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	if req.Price == nil && req.Description == nil && req.Category == nil && req.Available == nil && req.LowStockThreshold == nil {
+		return nil, domain.ErrInvalidItem
+	}
+	if (req.Price != nil && !validPrice(*req.Price)) ||
+		(req.Description != nil && !validDescription(*req.Description)) ||
+		(req.Category != nil && !categoryRe.MatchString(*req.Category)) ||
+		(req.LowStockThreshold != nil && !validStock(*req.LowStockThreshold)) {
+		return nil, domain.ErrInvalidItem
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	update := entity.ItemUpdate{
+		Price:             req.Price,
+		Description:       req.Description,
+		Category:          req.Category,
+		Available:         req.Available,
+		LowStockThreshold: req.LowStockThreshold,
+	}
+	updated, err := c.repo.UpdateItem(ctx, itemType, update, adminID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update catalog item")
+	}
+
+	res := catalogItemResponse(*updated)
+	return &res, nil
+}
+
+func (c Catalog) Retire(ctx context.Context, adminIDStr string, itemType string) (*domain.CatalogItem, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	retired, err := c.repo.RetireItem(ctx, itemType, adminID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retire catalog item")
+	}
+
+	res := catalogItemResponse(*retired)
+	return &res, nil
+}
+
+func (c Catalog) Restock(ctx context.Context, adminIDStr string, itemType string, req domain.RestockRequest) (*domain.CatalogItem, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	if req.Variant != "" && !skuRe.MatchString(req.Variant) {
+		return nil, domain.ErrVariantNotFound
+	}
+
+	if req.Quantity <= 0 || req.Quantity > maxItemStock {
+		return nil, domain.ErrInvalidAmount
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	restocked, err := c.repo.RestockItem(ctx, itemType, req.Variant, req.Quantity, adminID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restock catalog item")
+	}
+
+	res := catalogItemResponse(*restocked)
+	return &res, nil
+}
+
+func (c Catalog) CreateVariant(
+	ctx context.Context,
+	adminIDStr string,
+	itemType string,
+	req domain.CreateVariantRequest,
+) (*domain.CatalogItem, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	if !skuRe.MatchString(req.Sku) ||
+		utf8.RuneCountInString(req.Size) > maxVariantLabel || utf8.RuneCountInString(req.Color) > maxVariantLabel ||
+		(req.Price != nil && !validPrice(*req.Price)) ||
+		(req.Stock != nil && !validStock(*req.Stock)) {
+		return nil, domain.ErrInvalidItem
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	variant := entity.ItemVariant{
+		Sku:   req.Sku,
+		Size:  req.Size,
+		Color: req.Color,
+		Price: req.Price,
+		Stock: req.Stock,
+	}
+	item, err := c.repo.CreateVariant(ctx, itemType, variant, adminID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create item variant")
+	}
+
+	res := catalogItemResponse(*item)
+	return &res, nil
+}
+
+func (c Catalog) PriceHistory(ctx context.Context, itemType string) (*domain.PriceHistoryResponse, error) {
+	if !validateItemType(itemType) {
+		return nil, domain.ErrItemNotFound
+	}
+
+	changes, err := c.repo.GetPriceHistory(ctx, itemType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get price history")
+	}
+
+	res := domain.PriceHistoryResponse{
+		Type:    itemType,
+		Changes: make([]domain.PriceChange, 0, len(changes)),
+	}
+	for _, change := range changes {
+		res.Changes = append(res.Changes, domain.PriceChange{
+			Change:            change.Change,
+			Variant:           change.Variant,
+			Price:             change.Price,
+			Description:       change.Description,
+			Category:          change.Category,
+			Available:         change.Available,
+			Stock:             change.Stock,
+			LowStockThreshold: change.LowStockThreshold,
+			ChangedBy:         optionalID(change.ChangedBy),
+			ChangedAt:         change.ChangedAt,
+		})
+	}
+
+	return &res, nil
+}
+
+func catalogItemResponse(item entity.CatalogItem) domain.CatalogItem {
+	var variants []domain.Variant
+	for _, variant := range item.Variants {
+		price := item.Price
+		if variant.Price != nil {
+			price = *variant.Price
+		}
+		variants = append(variants, domain.Variant{
+			Sku:   variant.Sku,
+			Size:  variant.Size,
+			Color: variant.Color,
+			Price: price,
+			Stock: variant.Stock,
+		})
+	}
+
+	return domain.CatalogItem{
+		Type:              item.Type,
+		Price:             item.Price,
+		Description:       item.Description,
+		Category:          item.Category,
+		Available:         item.Available,
+		Stock:             item.Stock,
+		LowStockThreshold: item.LowStockThreshold,
+		RetiredAt:         item.RetiredAt,
+		Variants:          variants,
+	}
+}
+
+func validPrice(price int64) bool {
+	return price >= 0 && price <= maxItemPrice
+}
+
+func validStock(stock int) bool {
+	return stock >= 0 && stock <= maxItemStock
+}
+
+func validDescription(description string) bool {
+	return utf8.RuneCountInString(description) <= maxItemDescription
+}
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
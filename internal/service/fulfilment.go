@@ -0,0 +1,149 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"slices"
+	"strconv"
+)
+
+type FulfilmentRepository interface {
+	ListOrders(ctx context.Context, filter entity.OrderFilter) ([]entity.Order, error)
+	GetOrderStatus(ctx context.Context, orderID int64) (string, error)
+	SetOrderStatus(ctx context.Context, orderID int64, from, to string, changedBy uuid.UUID) (*entity.Order, error)
+	CancelOrder(ctx context.Context, orderID int64, from string, changedBy uuid.UUID) (*entity.Order, error)
+}
+
+// orderTransitions lists the statuses an order may move to from each status.
+// Picked up and cancelled orders are final.
+var orderTransitions = map[string][]string{
+	entity.OrderCreated: {entity.OrderReady, entity.OrderCancelled},
+	entity.OrderReady:   {entity.OrderPickedUp, entity.OrderCancelled},
+}
+
+// openOrderStatuses are the orders the office still has to hand out.
+var openOrderStatuses = []string{entity.OrderCreated, entity.OrderReady}
+
+type Fulfilment struct {
+	repo FulfilmentRepository
+}
+
+func NewFulfilment(repo FulfilmentRepository) Fulfilment {
+	return Fulfilment{
+		repo: repo,
+	}
+}
+
+// Advance moves an order to the requested status if the workflow allows it.
+// Cancelling refunds everything on the order that has not been returned yet.
+func (f Fulfilment) Advance(ctx context.Context, adminIDStr string, orderIDStr string, req domain.OrderStatusRequest) (*domain.Order, error) {
+	if !validateUUID(adminIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	orderID, err := strconv.ParseInt(orderIDStr, 10, 64)
+	if err != nil || orderID <= 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	if !validOrderStatus(req.Status) {
+		return nil, domain.ErrInvalidOrderStatus
+	}
+
+	adminID, _ := uuid.Parse(adminIDStr)
+
+	current, err := f.repo.GetOrderStatus(ctx, orderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get order status")
+	}
+
+	if !slices.Contains(orderTransitions[current], req.Status) {
+		return nil, domain.ErrInvalidOrderTransition
+	}
+
+	var order *entity.Order
+	if req.Status == entity.OrderCancelled {
+		order, err = f.repo.CancelOrder(ctx, orderID, current, adminID)
+	} else {
+		order, err = f.repo.SetOrderStatus(ctx, orderID, current, req.Status, adminID)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to change order status")
+	}
+
+	res := orderResponse(*order)
+	return &res, nil
+}
+
+// List returns one page of every user's orders in the given status, or of all
+// open orders when no status is given, newest first.
+func (f Fulfilment) List(ctx context.Context, req domain.AdminOrdersRequest) (*domain.OrdersResponse, error) {
+	filter := entity.OrderFilter{Statuses: openOrderStatuses}
+	if req.Status != "" {
+		if !validOrderStatus(req.Status) {
+			return nil, domain.ErrInvalidOrderStatus
+		}
+		filter.Statuses = []string{req.Status}
+	}
+
+	return f.list(ctx, filter, req.Cursor, req.Limit)
+}
+
+// Open returns one page of the user's orders that have not been picked up or
+// cancelled yet, newest first.
+func (f Fulfilment) Open(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	return f.list(ctx, entity.OrderFilter{UserId: &userID, Statuses: openOrderStatuses}, req.Cursor, req.Limit)
+}
+
+func (f Fulfilment) list(ctx context.Context, filter entity.OrderFilter, cursor string, limit int) (*domain.OrdersResponse, error) {
+	var err error
+	if filter.Limit, err = pageSize(limit); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodePageCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = &after
+	}
+
+	orders, err := f.repo.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list orders")
+	}
+
+	res := domain.OrdersResponse{
+		Orders: make([]domain.Order, 0, len(orders)),
+	}
+	if len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+		last := orders[len(orders)-1]
+		res.NextCursor = encodePageCursor(entity.PageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, order := range orders {
+		res.Orders = append(res.Orders, orderResponse(order))
+	}
+
+	return &res, nil
+}
+
+func validOrderStatus(status string) bool {
+	switch status {
+	case entity.OrderCreated, entity.OrderReady, entity.OrderPickedUp, entity.OrderCancelled:
+		return true
+	default:
+		return false
+	}
+}
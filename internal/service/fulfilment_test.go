@@ -0,0 +1,200 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type MockFulfilmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockFulfilmentRepository) ListOrders(ctx context.Context, filter entity.OrderFilter) ([]entity.Order, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]entity.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockFulfilmentRepository) GetOrderStatus(ctx context.Context, orderID int64) (string, error) {
+	args := m.Called(ctx, orderID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFulfilmentRepository) SetOrderStatus(
+	ctx context.Context,
+	orderID int64,
+	from, to string,
+	changedBy uuid.UUID,
+) (*entity.Order, error) {
+	args := m.Called(ctx, orderID, from, to, changedBy)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockFulfilmentRepository) CancelOrder(ctx context.Context, orderID int64, from string, changedBy uuid.UUID) (*entity.Order, error) {
+	args := m.Called(ctx, orderID, from, changedBy)
+	if args.Get(0) != nil {
+		return args.Get(0).(*entity.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestFulfilment_Advance(t *testing.T) {
+	const orderID = int64(42)
+	adminID := uuid.New()
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		allowed bool
+	}{
+		{name: "created to ready", from: entity.OrderCreated, to: entity.OrderReady, allowed: true},
+		{name: "created to cancelled", from: entity.OrderCreated, to: entity.OrderCancelled, allowed: true},
+		{name: "created to picked up", from: entity.OrderCreated, to: entity.OrderPickedUp},
+		{name: "created to created", from: entity.OrderCreated, to: entity.OrderCreated},
+		{name: "ready to picked up", from: entity.OrderReady, to: entity.OrderPickedUp, allowed: true},
+		{name: "ready to cancelled", from: entity.OrderReady, to: entity.OrderCancelled, allowed: true},
+		{name: "ready to created", from: entity.OrderReady, to: entity.OrderCreated},
+		{name: "ready to ready", from: entity.OrderReady, to: entity.OrderReady},
+		{name: "picked up to cancelled", from: entity.OrderPickedUp, to: entity.OrderCancelled},
+		{name: "picked up to ready", from: entity.OrderPickedUp, to: entity.OrderReady},
+		{name: "picked up to created", from: entity.OrderPickedUp, to: entity.OrderCreated},
+		{name: "picked up to picked up", from: entity.OrderPickedUp, to: entity.OrderPickedUp},
+		{name: "cancelled to ready", from: entity.OrderCancelled, to: entity.OrderReady},
+		{name: "cancelled to created", from: entity.OrderCancelled, to: entity.OrderCreated},
+		{name: "cancelled to picked up", from: entity.OrderCancelled, to: entity.OrderPickedUp},
+		{name: "cancelled to cancelled", from: entity.OrderCancelled, to: entity.OrderCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockFulfilmentRepository)
+			fulfilment := NewFulfilment(repo)
+
+			repo.On("GetOrderStatus", mock.Anything, orderID).Return(tt.from, nil).Once()
+			changed := &entity.Order{Id: orderID, Status: tt.to}
+			if tt.allowed && tt.to == entity.OrderCancelled {
+				repo.On("CancelOrder", mock.Anything, orderID, tt.from, adminID).Return(changed, nil).Once()
+			} else if tt.allowed {
+				repo.On("SetOrderStatus", mock.Anything, orderID, tt.from, tt.to, adminID).Return(changed, nil).Once()
+			}
+
+			order, err := fulfilment.Advance(context.Background(), adminID.String(), "42", domain.OrderStatusRequest{Status: tt.to})
+			if tt.allowed {
+				require.NoError(t, err)
+				require.Equal(t, tt.to, order.Status)
+			} else {
+				require.ErrorIs(t, err, domain.ErrInvalidOrderTransition)
+				repo.AssertNotCalled(t, "SetOrderStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				repo.AssertNotCalled(t, "CancelOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFulfilment_AdvanceRejectsInput(t *testing.T) {
+	adminID := uuid.New().String()
+
+	tests := []struct {
+		name     string
+		adminID  string
+		orderID  string
+		status   string
+		mock     func(repo *MockFulfilmentRepository)
+		expected error
+	}{
+		{
+			name:     "Invalid Admin",
+			adminID:  "not-a-uuid",
+			orderID:  "42",
+			status:   entity.OrderReady,
+			mock:     func(repo *MockFulfilmentRepository) {},
+			expected: domain.ErrInvalidCredentials,
+		},
+		{
+			name:     "Invalid Order Id",
+			adminID:  adminID,
+			orderID:  "abc",
+			status:   entity.OrderReady,
+			mock:     func(repo *MockFulfilmentRepository) {},
+			expected: domain.ErrOrderNotFound,
+		},
+		{
+			name:     "Non-positive Order Id",
+			adminID:  adminID,
+			orderID:  "0",
+			status:   entity.OrderReady,
+			mock:     func(repo *MockFulfilmentRepository) {},
+			expected: domain.ErrOrderNotFound,
+		},
+		{
+			name:     "Unknown Status",
+			adminID:  adminID,
+			orderID:  "42",
+			status:   "shipped",
+			mock:     func(repo *MockFulfilmentRepository) {},
+			expected: domain.ErrInvalidOrderStatus,
+		},
+		{
+			name:    "Order Not Found",
+			adminID: adminID,
+			orderID: "42",
+			status:  entity.OrderReady,
+			mock: func(repo *MockFulfilmentRepository) {
+				repo.On("GetOrderStatus", mock.Anything, int64(42)).Return("", domain.ErrOrderNotFound).Once()
+			},
+			expected: domain.ErrOrderNotFound,
+		},
+		{
+			name:    "Concurrent Change",
+			adminID: adminID,
+			orderID: "42",
+			status:  entity.OrderReady,
+			mock: func(repo *MockFulfilmentRepository) {
+				repo.On("GetOrderStatus", mock.Anything, int64(42)).Return(entity.OrderCreated, nil).Once()
+				repo.On("SetOrderStatus", mock.Anything, int64(42), entity.OrderCreated, entity.OrderReady, mock.Anything).
+					Return(nil, domain.ErrInvalidOrderTransition).Once()
+			},
+			expected: domain.ErrInvalidOrderTransition,
+		},
+		{
+			name:    "Repository Error",
+			adminID: adminID,
+			orderID: "42",
+			status:  entity.OrderCancelled,
+			mock: func(repo *MockFulfilmentRepository) {
+				repo.On("GetOrderStatus", mock.Anything, int64(42)).Return(entity.OrderReady, nil).Once()
+				repo.On("CancelOrder", mock.Anything, int64(42), entity.OrderReady, mock.Anything).
+					Return(nil, errors.New("db error")).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockFulfilmentRepository)
+			fulfilment := NewFulfilment(repo)
+			tt.mock(repo)
+
+			order, err := fulfilment.Advance(context.Background(), tt.adminID, tt.orderID, domain.OrderStatusRequest{Status: tt.status})
+			require.Error(t, err)
+			require.Nil(t, order)
+			if tt.expected != nil {
+				require.ErrorIs(t, err, tt.expected)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"unicode/utf8"
+)
+
+const maxGiftMessageLength = 500
+
+// Gift sends an item to another user. A non-nil response means the idempotency
+// key has already been used and the gift was not repeated.
+func (t Transaction) Gift(ctx context.Context, userIDStr string, req domain.GiftRequest, idempotencyKey string) (*domain.StoredResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	gift, err := newSendGift(req)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	key, err := newIdempotencyKey(userID, idempotencyKey, "gift", req)
+	if err != nil {
+		return nil, err
+	}
+
+	order, replay, err := t.repo.SendGift(ctx, userID, gift, key)
+	if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		return nil, domain.ErrIdempotencyKeyMismatch
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send gift")
+	}
+
+	if order != nil {
+		t.publishLowStock(ctx, order.LowStock)
+	}
+
+	return storedResponse(replay), nil
+}
+
+func newSendGift(req domain.GiftRequest) (entity.SendGift, error) {
+	gift := entity.SendGift{
+		ToUser:   req.ToUser,
+		ItemType: req.Type,
+		Variant:  req.Variant,
+		Quantity: req.Quantity,
+		Source:   req.Source,
+		Message:  req.Message,
+	}
+	if gift.Quantity == 0 {
+		gift.Quantity = 1
+	}
+	if gift.Source == "" {
+		gift.Source = entity.GiftSourcePurchase
+	}
+
+	if !validateUsername(gift.ToUser) {
+		return gift, domain.ErrInvalidInput
+	}
+
+	if !validateItemType(gift.ItemType) || (gift.Variant != "" && !skuRe.MatchString(gift.Variant)) ||
+		gift.Quantity <= 0 || gift.Quantity > maxLineQuantity ||
+		(gift.Source != entity.GiftSourcePurchase && gift.Source != entity.GiftSourceInventory) ||
+		utf8.RuneCountInString(gift.Message) > maxGiftMessageLength {
+		return gift, domain.ErrInvalidGift
+	}
+
+	return gift, nil
+}
+
+func giftResponses(gifts []entity.Gift, showFrom bool) []domain.Gift {
+	res := make([]domain.Gift, 0, len(gifts))
+	for _, gift := range gifts {
+		item := domain.Gift{
+			Type:      gift.ItemType,
+			Variant:   gift.Variant,
+			Quantity:  gift.Quantity,
+			Source:    gift.Source,
+			Message:   gift.Message,
+			CreatedAt: gift.CreatedAt,
+		}
+		if showFrom {
+			item.FromUser = gift.FromUser
+		} else {
+			item.ToUser = gift.ToUser
+		}
+		res = append(res, item)
+	}
+	return res
+}
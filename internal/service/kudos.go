@@ -0,0 +1,117 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type KudosRepository interface {
+	ListKudos(ctx context.Context, filter entity.KudosFilter) ([]entity.Kudos, error)
+}
+
+const maxKudosMessageLength = 200
+
+type Kudos struct {
+	repo KudosRepository
+}
+
+func NewKudos(repo KudosRepository) Kudos {
+	return Kudos{
+		repo: repo,
+	}
+}
+
+// Feed returns one page of the public thank-yous across the company, newest
+// first, optionally limited to one category.
+func (k Kudos) Feed(ctx context.Context, req domain.KudosRequest) (*domain.KudosResponse, error) {
+	if req.Category != "" && !validKudosCategory(req.Category) {
+		return nil, domain.ErrInvalidKudos
+	}
+
+	filter := entity.KudosFilter{Category: req.Category}
+
+	var err error
+	if filter.Limit, err = pageSize(req.Limit); err != nil {
+		return nil, err
+	}
+
+	if req.Cursor != "" {
+		after, err := decodePageCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = &after
+	}
+
+	kudos, err := k.repo.ListKudos(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list kudos")
+	}
+
+	res := domain.KudosResponse{
+		Kudos: make([]domain.Kudos, 0, len(kudos)),
+	}
+	if len(kudos) > filter.Limit {
+		kudos = kudos[:filter.Limit]
+		last := kudos[len(kudos)-1]
+		res.NextCursor = encodePageCursor(entity.PageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, item := range kudos {
+		res.Kudos = append(res.Kudos, domain.Kudos{
+			ID:        item.Id,
+			FromUser:  item.FromUser,
+			ToUser:    item.ToUser,
+			Category:  item.Category,
+			Message:   item.Message,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	return &res, nil
+}
+
+func validKudosCategory(category string) bool {
+	switch category {
+	case entity.KudosHelpedMe, entity.KudosGreatReview, entity.KudosOnboarding, entity.KudosTeamwork:
+		return true
+	default:
+		return false
+	}
+}
+
+func sanitizeKudosMessage(message string) (string, error) {
+	message, ok := sanitizeMessage(message, maxKudosMessageLength)
+	if !ok {
+		return "", domain.ErrInvalidKudos
+	}
+	return message, nil
+}
+
+// sanitizeMessage drops control and format characters and collapses runs of
+// whitespace, so a message renders as one line. The length limit applies to
+// the cleaned message.
+func sanitizeMessage(message string, maxLength int) (string, bool) {
+	if !utf8.ValidString(message) {
+		return "", false
+	}
+
+	message = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			return -1
+		default:
+			return r
+		}
+	}, message)
+	message = strings.Join(strings.Fields(message), " ")
+
+	return message, utf8.RuneCountInString(message) <= maxLength
+}
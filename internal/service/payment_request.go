@@ -0,0 +1,188 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+type PaymentRequestRepository interface {
+	CreatePaymentRequest(ctx context.Context, requesterID uuid.UUID, req entity.NewPaymentRequest) (*entity.PaymentRequest, error)
+	ListPaymentRequests(ctx context.Context, filter entity.PaymentRequestFilter) ([]entity.PaymentRequest, error)
+	ApprovePaymentRequest(ctx context.Context, payerID uuid.UUID, requestID int64) (*entity.PaymentRequest, error)
+	DeclinePaymentRequest(ctx context.Context, payerID uuid.UUID, requestID int64) (*entity.PaymentRequest, error)
+}
+
+const maxPaymentRequestMessageLength = 200
+
+type PaymentRequests struct {
+	repo PaymentRequestRepository
+	ttl  time.Duration
+}
+
+func NewPaymentRequests(repo PaymentRequestRepository, ttl time.Duration) PaymentRequests {
+	return PaymentRequests{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Create asks another user for coins. The request can be approved or declined
+// until its TTL runs out.
+func (p PaymentRequests) Create(ctx context.Context, userIDStr string, req domain.CreatePaymentRequest) (*domain.PaymentRequest, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if !validateUsername(req.FromUser) {
+		return nil, domain.ErrInvalidInput
+	}
+
+	message, ok := sanitizeMessage(req.Message, maxPaymentRequestMessageLength)
+	if !ok || req.Amount <= 0 {
+		return nil, domain.ErrInvalidPaymentRequest
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	request, err := p.repo.CreatePaymentRequest(ctx, userID, entity.NewPaymentRequest{
+		Payer:   req.FromUser,
+		Amount:  req.Amount,
+		Message: message,
+		TTL:     p.ttl,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create payment request")
+	}
+
+	res := paymentRequestResponse(*request)
+	return &res, nil
+}
+
+// Incoming returns one page of the pending requests the user has been asked
+// to pay, newest first.
+func (p PaymentRequests) Incoming(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	filter := entity.PaymentRequestFilter{PayerId: &userID, Status: entity.PaymentRequestPending}
+	return p.list(ctx, filter, req.Cursor, req.Limit)
+}
+
+// Sent returns one page of the requests the user has made in any status,
+// including expired and declined ones, newest first.
+func (p PaymentRequests) Sent(ctx context.Context, userIDStr string, req domain.PaymentRequestsRequest) (*domain.PaymentRequestsResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if req.Status != "" && !validPaymentRequestStatus(req.Status) {
+		return nil, domain.ErrInvalidPaymentStatus
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	filter := entity.PaymentRequestFilter{RequesterId: &userID, Status: req.Status}
+	return p.list(ctx, filter, req.Cursor, req.Limit)
+}
+
+// Approve pays a pending request addressed to the user.
+func (p PaymentRequests) Approve(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error) {
+	return p.resolve(ctx, userIDStr, requestIDStr, p.repo.ApprovePaymentRequest)
+}
+
+// Decline rejects a pending request addressed to the user without paying it.
+func (p PaymentRequests) Decline(ctx context.Context, userIDStr string, requestIDStr string) (*domain.PaymentRequest, error) {
+	return p.resolve(ctx, userIDStr, requestIDStr, p.repo.DeclinePaymentRequest)
+}
+
+func (p PaymentRequests) resolve(
+	ctx context.Context,
+	userIDStr string,
+	requestIDStr string,
+	resolve func(ctx context.Context, payerID uuid.UUID, requestID int64) (*entity.PaymentRequest, error),
+) (*domain.PaymentRequest, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	requestID, err := strconv.ParseInt(requestIDStr, 10, 64)
+	if err != nil || requestID <= 0 {
+		return nil, domain.ErrPaymentRequestNotFound
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	request, err := resolve(ctx, userID, requestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve payment request")
+	}
+
+	res := paymentRequestResponse(*request)
+	return &res, nil
+}
+
+func (p PaymentRequests) list(ctx context.Context, filter entity.PaymentRequestFilter, cursor string, limit int) (*domain.PaymentRequestsResponse, error) {
+	var err error
+	if filter.Limit, err = pageSize(limit); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		after, err := decodePageCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = &after
+	}
+
+	requests, err := p.repo.ListPaymentRequests(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list payment requests")
+	}
+
+	res := domain.PaymentRequestsResponse{
+		Requests: make([]domain.PaymentRequest, 0, len(requests)),
+	}
+	if len(requests) > filter.Limit {
+		requests = requests[:filter.Limit]
+		last := requests[len(requests)-1]
+		res.NextCursor = encodePageCursor(entity.PageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, request := range requests {
+		res.Requests = append(res.Requests, paymentRequestResponse(request))
+	}
+
+	return &res, nil
+}
+
+func validPaymentRequestStatus(status string) bool {
+	switch status {
+	case entity.PaymentRequestPending, entity.PaymentRequestApproved, entity.PaymentRequestDeclined, entity.PaymentRequestExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+func paymentRequestResponse(request entity.PaymentRequest) domain.PaymentRequest {
+	return domain.PaymentRequest{
+		ID:         request.Id,
+		FromUser:   request.Payer,
+		ToUser:     request.Requester,
+		Amount:     request.Amount,
+		Message:    request.Message,
+		Status:     request.Status,
+		ExpiresAt:  request.ExpiresAt,
+		ResolvedAt: request.ResolvedAt,
+		CreatedAt:  request.CreatedAt,
+	}
+}
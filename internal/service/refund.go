@@ -0,0 +1,97 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"avito_test/internal/entity"
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+// Return hands back items from one order line and refunds what was paid for
+// them. The bool reports whether the idempotency key had already been used, in
+// which case the original refund is returned and nothing is refunded again.
+func (t Transaction) Return(
+	ctx context.Context,
+	userIDStr string,
+	req domain.ReturnRequest,
+	idempotencyKey string,
+) (*domain.Refund, bool, error) {
+	if !validateUUID(userIDStr) {
+		return nil, false, domain.ErrInvalidCredentials
+	}
+
+	ret, err := newReturnItem(req)
+	if err != nil {
+		return nil, false, err
+	}
+	ret.PurchasedAfter = time.Now().Add(-t.returnWindow)
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	key, err := newIdempotencyKey(userID, idempotencyKey, "return", req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		refund *entity.Refund
+		replay *entity.IdempotencyKey
+	)
+	err = t.txManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		refund, replay, err = t.repo.ReturnItem(ctx, userID, ret, key)
+		if err != nil || replay == nil {
+			return err
+		}
+
+		refundID, err := strconv.ParseInt(string(replay.Response), 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode replayed refund")
+		}
+		refund, err = t.repo.GetRefund(ctx, userID, refundID)
+		return errors.Wrap(err, "failed to get replayed refund")
+	})
+	if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		return nil, false, domain.ErrIdempotencyKeyMismatch
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to return item")
+	}
+
+	res := refundResponse(*refund)
+	return &res, replay != nil, nil
+}
+
+func newReturnItem(req domain.ReturnRequest) (entity.ReturnItem, error) {
+	ret := entity.ReturnItem{
+		OrderId:  req.OrderID,
+		ItemType: req.Type,
+		Variant:  req.Variant,
+		Quantity: req.Quantity,
+	}
+	if ret.Quantity == 0 {
+		ret.Quantity = 1
+	}
+
+	if ret.OrderId <= 0 || !validateItemType(ret.ItemType) || (ret.Variant != "" && !skuRe.MatchString(ret.Variant)) ||
+		ret.Quantity <= 0 || ret.Quantity > maxLineQuantity {
+		return ret, domain.ErrInvalidReturn
+	}
+
+	return ret, nil
+}
+
+func refundResponse(refund entity.Refund) domain.Refund {
+	return domain.Refund{
+		ID:        refund.Id,
+		OrderID:   refund.OrderId,
+		ItemType:  refund.ItemType,
+		Variant:   refund.Variant,
+		Quantity:  refund.Quantity,
+		Amount:    refund.Amount,
+		CreatedAt: refund.CreatedAt,
+	}
+}
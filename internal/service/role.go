@@ -0,0 +1,54 @@
+package service
+
+import (
+	"avito_test/internal/domain"
+	"context"
+	"github.com/pkg/errors"
+)
+
+type RoleRepository interface {
+	AddRole(ctx context.Context, username, role string) ([]string, error)
+	RemoveRole(ctx context.Context, username, role string) ([]string, error)
+}
+
+type Role struct {
+	repo RoleRepository
+}
+
+func NewRole(repo RoleRepository) Role {
+	return Role{
+		repo: repo,
+	}
+}
+
+func (r Role) Grant(ctx context.Context, username string, req domain.RoleRequest) (*domain.RolesResponse, error) {
+	if !domain.ValidRole(req.Role) {
+		return nil, domain.ErrInvalidRole
+	}
+
+	roles, err := r.repo.AddRole(ctx, username, req.Role)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to grant role")
+	}
+
+	return &domain.RolesResponse{Username: username, Roles: roles}, nil
+}
+
+func (r Role) Revoke(ctx context.Context, username string, role string) (*domain.RolesResponse, error) {
+	if !domain.ValidRole(role) {
+		return nil, domain.ErrInvalidRole
+	}
+
+	roles, err := r.repo.RemoveRole(ctx, username, role)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to revoke role")
+	}
+
+	return &domain.RolesResponse{Username: username, Roles: roles}, nil
+}
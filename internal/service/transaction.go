@@ -4,64 +4,191 @@ import (
 	"avito_test/internal/domain"
 	"avito_test/internal/entity"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	maxUsernameLength       = 255
+	defaultPageSize         = 20
+	maxPageSize             = 100
+	maxCartLines            = 50
+	maxLineQuantity         = 100
 )
 
 type TransactionRepository interface {
-	BuyItem(ctx context.Context, userID uuid.UUID, itemType string) error
-	SendCoin(ctx context.Context, userID uuid.UUID, send entity.SendCoin) error
-	GetInfo(ctx context.Context, userID uuid.UUID) (*entity.Info, error)
+	BuyItem(
+		ctx context.Context,
+		userID uuid.UUID,
+		itemType string,
+		variant string,
+		key *entity.IdempotencyKey,
+	) (*entity.Order, *entity.IdempotencyKey, error)
+	SendCoin(ctx context.Context, userID uuid.UUID, send entity.SendCoin, key *entity.IdempotencyKey) (*entity.IdempotencyKey, error)
+	GetInfo(ctx context.Context, userID uuid.UUID, historyLimit int) (*entity.Info, error)
+	GetHistory(ctx context.Context, userID uuid.UUID, filter entity.HistoryFilter) ([]entity.HistoryEntry, error)
+	GetOrders(ctx context.Context, userID uuid.UUID, after *entity.PageCursor, limit int) ([]entity.Order, error)
+	GetOrder(ctx context.Context, userID uuid.UUID, orderID int64) (*entity.Order, error)
+	Checkout(
+		ctx context.Context,
+		userID uuid.UUID,
+		lines []entity.OrderLine,
+		key *entity.IdempotencyKey,
+	) (*entity.Order, *entity.IdempotencyKey, error)
+	SendGift(ctx context.Context, userID uuid.UUID, gift entity.SendGift, key *entity.IdempotencyKey) (*entity.Order, *entity.IdempotencyKey, error)
+	ReturnItem(ctx context.Context, userID uuid.UUID, ret entity.ReturnItem, key *entity.IdempotencyKey) (*entity.Refund, *entity.IdempotencyKey, error)
+	GetRefund(ctx context.Context, userID uuid.UUID, refundID int64) (*entity.Refund, error)
+	AuditOrder(ctx context.Context, userID uuid.UUID, orderID int64, action string) error
+}
+
+type StockEvents interface {
+	PublishLowStock(ctx context.Context, level entity.StockLevel)
+}
+
+// TxManager runs several repository calls in one database transaction.
+type TxManager interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type Transaction struct {
-	repo TransactionRepository
+	repo             TransactionRepository
+	txManager        TxManager
+	events           StockEvents
+	infoHistoryLimit int
+	returnWindow     time.Duration
 }
 
-func NewTransaction(repo TransactionRepository) Transaction {
+func NewTransaction(
+	repo TransactionRepository,
+	txManager TxManager,
+	events StockEvents,
+	infoHistoryLimit int,
+	returnWindow time.Duration,
+) Transaction {
 	return Transaction{
-		repo: repo,
+		repo:             repo,
+		txManager:        txManager,
+		events:           events,
+		infoHistoryLimit: infoHistoryLimit,
+		returnWindow:     returnWindow,
 	}
 }
 
-func (t Transaction) Buy(ctx context.Context, userIDStr string, itemType string) error {
+// Buy purchases an item, or the given variant of it. A non-nil response means
+// the idempotency key has already been used and the purchase was not repeated.
+func (t Transaction) Buy(
+	ctx context.Context,
+	userIDStr string,
+	itemType string,
+	variant string,
+	idempotencyKey string,
+) (*domain.StoredResponse, error) {
 	if !validateUUID(userIDStr) {
-		return domain.ErrInvalidCredentials
+		return nil, domain.ErrInvalidCredentials
 	}
 
 	if !validateItemType(itemType) {
-		return domain.ErrInvalidCredentials
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if variant != "" && !skuRe.MatchString(variant) {
+		return nil, domain.ErrVariantNotFound
 	}
 
 	userID, _ := uuid.Parse(userIDStr)
 
-	err := t.repo.BuyItem(ctx, userID, itemType)
+	// Purchases without a variant keep hashing the bare item type, so keys stored
+	// before variants existed still match their replays.
+	payload := any(itemType)
+	if variant != "" {
+		payload = domain.CartLine{Type: itemType, Variant: variant, Quantity: 1}
+	}
+	key, err := newIdempotencyKey(userID, idempotencyKey, "buy", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order and its audit entry commit together.
+	var (
+		order  *entity.Order
+		replay *entity.IdempotencyKey
+	)
+	err = t.txManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		order, replay, err = t.repo.BuyItem(ctx, userID, itemType, variant, key)
+		if err != nil || replay != nil {
+			return err
+		}
+
+		return t.repo.AuditOrder(ctx, userID, order.Id, entity.OrderAuditBuy)
+	})
+	if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		return nil, domain.ErrIdempotencyKeyMismatch
+	}
 	if err != nil {
-		return errors.Wrap(err, "failed to buy item")
+		return nil, errors.Wrap(err, "failed to buy item")
 	}
 
-	return nil
+	if order != nil {
+		t.publishLowStock(ctx, order.LowStock)
+	}
+
+	return storedResponse(replay), nil
 }
 
-func (t Transaction) Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest) error {
+// Send transfers coins. A non-nil response means the idempotency key has
+// already been used and the transfer was not repeated.
+func (t Transaction) Send(ctx context.Context, userIDStr string, req domain.SendCoinRequest, idempotencyKey string) (*domain.StoredResponse, error) {
 	if !validateUUID(userIDStr) {
-		return domain.ErrInvalidCredentials
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if err := validateSendCoinRequest(req); err != nil {
+		return nil, err
+	}
+
+	message, err := sanitizeKudosMessage(req.Message)
+	if err != nil {
+		return nil, err
+	}
+	if req.Category != "" && !validKudosCategory(req.Category) {
+		return nil, domain.ErrInvalidKudos
 	}
 
 	userID, _ := uuid.Parse(userIDStr)
 
 	entitySendCoin := entity.SendCoin{
-		ToUser: req.ToUser,
-		Amount: req.Amount,
+		ToUser:   req.ToUser,
+		Amount:   req.Amount,
+		Message:  message,
+		Category: req.Category,
+		Private:  req.Private,
 	}
 
-	err := t.repo.SendCoin(ctx, userID, entitySendCoin)
+	key, err := newIdempotencyKey(userID, idempotencyKey, "sendCoin", req)
 	if err != nil {
-		return errors.Wrap(err, "failed to send coin")
+		return nil, err
 	}
 
-	return nil
+	replay, err := t.repo.SendCoin(ctx, userID, entitySendCoin, key)
+	if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		return nil, domain.ErrIdempotencyKeyMismatch
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send coin")
+	}
+
+	return storedResponse(replay), nil
 }
 
 func (t Transaction) Info(ctx context.Context, userIDStr string) (*domain.InfoResponse, error) {
@@ -71,7 +198,7 @@ func (t Transaction) Info(ctx context.Context, userIDStr string) (*domain.InfoRe
 
 	userID, _ := uuid.Parse(userIDStr)
 
-	info, err := t.repo.GetInfo(ctx, userID)
+	info, err := t.repo.GetInfo(ctx, userID, t.infoHistoryLimit)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get user info")
 	}
@@ -83,6 +210,7 @@ func (t Transaction) Info(ctx context.Context, userIDStr string) (*domain.InfoRe
 	for _, item := range info.Inventory {
 		inventory = append(inventory, domain.Item{
 			Type:     item.Type,
+			Variant:  item.Variant,
 			Quantity: item.Quantity,
 		})
 	}
@@ -101,6 +229,11 @@ func (t Transaction) Info(ctx context.Context, userIDStr string) (*domain.InfoRe
 		})
 	}
 
+	recentPurchases := make([]domain.Order, 0, len(info.RecentPurchases))
+	for _, order := range info.RecentPurchases {
+		recentPurchases = append(recentPurchases, orderResponse(order))
+	}
+
 	res := domain.InfoResponse{
 		Coins:     info.Coins,
 		Inventory: inventory,
@@ -108,11 +241,384 @@ func (t Transaction) Info(ctx context.Context, userIDStr string) (*domain.InfoRe
 			Received: receivedTransactions,
 			Sent:     sentTransactions,
 		},
+		RecentPurchases: recentPurchases,
+		Gifted:          giftResponses(info.Gifted, false),
+		ReceivedGifts:   giftResponses(info.ReceivedGifts, true),
+		Refunds:         make([]domain.Refund, 0, len(info.Refunds)),
+	}
+	for _, refund := range info.Refunds {
+		res.Refunds = append(res.Refunds, refundResponse(refund))
 	}
 
 	return &res, nil
 }
 
+// History returns one page of the user's coin history, newest first. Pass
+// NextCursor from the response back as Cursor to fetch the following page.
+func (t Transaction) History(ctx context.Context, userIDStr string, req domain.HistoryRequest) (*domain.HistoryResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	filter, err := newHistoryFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	entries, err := t.repo.GetHistory(ctx, userID, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get coin history")
+	}
+
+	res := domain.HistoryResponse{
+		Transactions: make([]domain.HistoryEntry, 0, len(entries)),
+	}
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		last := entries[len(entries)-1]
+		res.NextCursor = encodePageCursor(entity.PageCursor{CreatedAt: last.CreatedAt, Kind: last.Kind, Id: last.Id})
+	}
+
+	for _, entry := range entries {
+		res.Transactions = append(res.Transactions, domain.HistoryEntry{
+			ID:        entry.Id,
+			Direction: entry.Direction,
+			FromUser:  entry.FromUser,
+			ToUser:    entry.ToUser,
+			Amount:    entry.Amount,
+			Message:   entry.Message,
+			Category:  entry.Category,
+			Private:   entry.Private,
+			OrderID:   entry.OrderId,
+			Status:    entry.Status,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	return &res, nil
+}
+
+// Orders returns one page of the user's purchases, newest first.
+func (t Transaction) Orders(ctx context.Context, userIDStr string, req domain.OrdersRequest) (*domain.OrdersResponse, error) {
+	if !validateUUID(userIDStr) {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	limit, err := pageSize(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *entity.PageCursor
+	if req.Cursor != "" {
+		cursor, err := decodePageCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &cursor
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	orders, err := t.repo.GetOrders(ctx, userID, after, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get orders")
+	}
+
+	res := domain.OrdersResponse{
+		Orders: make([]domain.Order, 0, len(orders)),
+	}
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[len(orders)-1]
+		res.NextCursor = encodePageCursor(entity.PageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, order := range orders {
+		res.Orders = append(res.Orders, orderResponse(order))
+	}
+
+	return &res, nil
+}
+
+// Checkout buys every cart line in one order and returns its receipt. The bool
+// reports whether the idempotency key had already been used, in which case the
+// receipt of the original order is returned and nothing is bought again.
+func (t Transaction) Checkout(
+	ctx context.Context,
+	userIDStr string,
+	req domain.CheckoutRequest,
+	idempotencyKey string,
+) (*domain.Order, bool, error) {
+	if !validateUUID(userIDStr) {
+		return nil, false, domain.ErrInvalidCredentials
+	}
+
+	lines, err := newOrderLines(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	userID, _ := uuid.Parse(userIDStr)
+
+	key, err := newIdempotencyKey(userID, idempotencyKey, "checkout", req)
+	if err != nil {
+		return nil, false, err
+	}
+	if key != nil {
+		key.StatusCode = http.StatusCreated
+	}
+
+	// A new order is audited in the transaction that placed it. A replay is answered
+	// with the original order, read in the same transaction that found the key so
+	// the receipt matches what the key was stored with.
+	var (
+		order  *entity.Order
+		replay *entity.IdempotencyKey
+	)
+	err = t.txManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		order, replay, err = t.repo.Checkout(ctx, userID, lines, key)
+		if err != nil {
+			return err
+		}
+		if replay == nil {
+			return t.repo.AuditOrder(ctx, userID, order.Id, entity.OrderAuditCheckout)
+		}
+
+		orderID, err := strconv.ParseInt(string(replay.Response), 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode replayed order")
+		}
+		order, err = t.repo.GetOrder(ctx, userID, orderID)
+		return errors.Wrap(err, "failed to get replayed order")
+	})
+	if errors.Is(err, domain.ErrIdempotencyKeyMismatch) {
+		return nil, false, domain.ErrIdempotencyKeyMismatch
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to checkout")
+	}
+
+	if replay == nil {
+		t.publishLowStock(ctx, order.LowStock)
+	}
+
+	res := orderResponse(*order)
+	return &res, replay != nil, nil
+}
+
+// publishLowStock is called only after the order has committed, so subscribers
+// never hear about stock that a rolled-back purchase did not take.
+func (t Transaction) publishLowStock(ctx context.Context, levels []entity.StockLevel) {
+	for _, level := range levels {
+		t.events.PublishLowStock(ctx, level)
+	}
+}
+
+func orderResponse(order entity.Order) domain.Order {
+	res := domain.Order{
+		ID:        order.Id,
+		User:      order.Username,
+		Status:    order.Status,
+		Lines:     make([]domain.OrderLine, 0, len(order.Lines)),
+		Total:     order.Total,
+		CreatedAt: order.CreatedAt,
+	}
+	for _, line := range order.Lines {
+		res.Lines = append(res.Lines, domain.OrderLine{
+			ItemType:  line.ItemType,
+			Variant:   line.Variant,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Total:     line.UnitPrice * int64(line.Quantity),
+			Returned:  line.Returned,
+		})
+	}
+	return res
+}
+
+// newOrderLines validates the cart and merges repeated item types into one line.
+func newOrderLines(req domain.CheckoutRequest) ([]entity.OrderLine, error) {
+	if len(req.Items) == 0 || len(req.Items) > maxCartLines {
+		return nil, domain.ErrInvalidCart
+	}
+
+	lines := make([]entity.OrderLine, 0, len(req.Items))
+	index := make(map[string]int, len(req.Items))
+	for _, item := range req.Items {
+		if !validateItemType(item.Type) || item.Quantity <= 0 || item.Quantity > maxLineQuantity ||
+			(item.Variant != "" && !skuRe.MatchString(item.Variant)) {
+			return nil, domain.ErrInvalidCart
+		}
+
+		key := item.Type + "/" + item.Variant
+		if i, ok := index[key]; ok {
+			lines[i].Quantity += item.Quantity
+			if lines[i].Quantity > maxLineQuantity {
+				return nil, domain.ErrInvalidCart
+			}
+			continue
+		}
+
+		index[key] = len(lines)
+		lines = append(lines, entity.OrderLine{ItemType: item.Type, Variant: item.Variant, Quantity: item.Quantity})
+	}
+
+	return lines, nil
+}
+
+// newIdempotencyKey binds the client key to the operation and its payload, so
+// reusing the key for a different request can be detected. An empty key
+// disables idempotency.
+func newIdempotencyKey(userID uuid.UUID, key string, operation string, payload any) (*entity.IdempotencyKey, error) {
+	if key == "" {
+		return nil, nil // nolint: nilnil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, domain.ErrInvalidIdempotencyKey
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request")
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(operation))
+	hash.Write([]byte{0})
+	hash.Write(body)
+
+	return &entity.IdempotencyKey{
+		UserId:      userID,
+		Key:         key,
+		RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		StatusCode:  http.StatusOK,
+	}, nil
+}
+
+func storedResponse(key *entity.IdempotencyKey) *domain.StoredResponse {
+	if key == nil {
+		return nil
+	}
+	return &domain.StoredResponse{
+		StatusCode: key.StatusCode,
+		Body:       key.Response,
+	}
+}
+
+func validateSendCoinRequest(req domain.SendCoinRequest) error {
+	if req.Amount <= 0 {
+		return domain.ErrInvalidAmount
+	}
+	if !validateUsername(req.ToUser) {
+		return domain.ErrInvalidInput
+	}
+	return nil
+}
+
+func newHistoryFilter(req domain.HistoryRequest) (entity.HistoryFilter, error) {
+	filter := entity.HistoryFilter{
+		Direction:    req.Direction,
+		Counterparty: req.Counterparty,
+		MinAmount:    req.MinAmount,
+		MaxAmount:    req.MaxAmount,
+		Limit:        req.Limit,
+	}
+
+	switch req.Direction {
+	case "", domain.DirectionSent, domain.DirectionReceived, domain.DirectionRefund, domain.DirectionRequest:
+	default:
+		return filter, domain.ErrInvalidHistoryFilter
+	}
+
+	if len(req.Counterparty) > maxUsernameLength {
+		return filter, domain.ErrInvalidHistoryFilter
+	}
+
+	if req.MinAmount < 0 || req.MaxAmount < 0 || (req.MaxAmount > 0 && req.MinAmount > req.MaxAmount) {
+		return filter, domain.ErrInvalidHistoryFilter
+	}
+
+	var err error
+	if req.From != "" {
+		if filter.From, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return filter, domain.ErrInvalidHistoryFilter
+		}
+	}
+	if req.To != "" {
+		if filter.To, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return filter, domain.ErrInvalidHistoryFilter
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, domain.ErrInvalidHistoryFilter
+	}
+
+	if filter.Limit, err = pageSize(req.Limit); err != nil {
+		return filter, domain.ErrInvalidHistoryFilter
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodePageCursor(req.Cursor)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+func pageSize(limit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, domain.ErrInvalidInput
+	case limit == 0:
+		return defaultPageSize, nil
+	case limit > maxPageSize:
+		return maxPageSize, nil
+	default:
+		return limit, nil
+	}
+}
+
+// Page cursors are opaque to clients: base64url("<unix nanos>:<id>").
+func encodePageCursor(cursor entity.PageCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + ":" + strconv.FormatInt(cursor.Id, 10)
+	if cursor.Kind != "" {
+		raw += ":" + cursor.Kind
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePageCursor(value string) (entity.PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return entity.PageCursor{}, domain.ErrInvalidCursor
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return entity.PageCursor{}, domain.ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return entity.PageCursor{}, domain.ErrInvalidCursor
+	}
+
+	cursor := entity.PageCursor{CreatedAt: time.Unix(0, createdAt).UTC()}
+	id, cursor.Kind, _ = strings.Cut(id, ":")
+	if cursor.Id, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return entity.PageCursor{}, domain.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
 func validateUUID(userIDStr string) bool {
 	_, err := uuid.Parse(userIDStr)
 	return err == nil
@@ -122,3 +628,7 @@ func validateItemType(itemType string) bool {
 	re := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
 	return re.MatchString(itemType)
 }
+
+func validateUsername(username string) bool {
+	return username != "" && len(username) <= maxUsernameLength
+}
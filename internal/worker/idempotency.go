@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"avito_test/pkg/logger"
+	"context"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// IdempotencySweeper periodically deletes idempotency keys older than the TTL.
+type IdempotencySweeper struct {
+	repo     IdempotencyRepository
+	ttl      time.Duration
+	interval time.Duration
+	logger   *logger.ApiLogger
+}
+
+func NewIdempotencySweeper(repo IdempotencyRepository, ttl, interval time.Duration, logger *logger.ApiLogger) IdempotencySweeper {
+	return IdempotencySweeper{
+		repo:     repo,
+		ttl:      ttl,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run sweeps once per interval until ctx is cancelled.
+func (s IdempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+func (s IdempotencySweeper) Sweep(ctx context.Context) {
+	deleted, err := s.repo.DeleteExpired(ctx, time.Now().Add(-s.ttl))
+	if err != nil {
+		s.logger.Errorf("failed to sweep idempotency keys: %v", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Infof("swept %d expired idempotency keys", deleted)
+	}
+}
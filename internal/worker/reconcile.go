@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"avito_test/internal/entity"
+	"avito_test/pkg/logger"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type LedgerRepository interface {
+	FindDrift(ctx context.Context) ([]entity.BalanceDrift, error)
+	CorrectBalance(ctx context.Context, drift entity.BalanceDrift) (bool, error)
+}
+
+type ReconcileReport struct {
+	CheckedAt time.Time             `json:"checkedAt"`
+	Drift     []entity.BalanceDrift `json:"drift"`
+	Corrected int                   `json:"corrected"`
+	// Negative lists drifted balances whose expected value is below zero. users.coin
+	// cannot go negative, so they are left for manual review instead of corrected.
+	Negative []entity.BalanceDrift `json:"negative"`
+}
+
+// Reconciler compares cached and ledger balances with the balances recomputed
+// from transfers, orders and refunds. The periodic job only reports drift;
+// corrections are left to cmd/reconcile.
+type Reconciler struct {
+	repo     LedgerRepository
+	interval time.Duration
+	logger   *logger.ApiLogger
+}
+
+func NewReconciler(repo LedgerRepository, interval time.Duration, logger *logger.ApiLogger) Reconciler {
+	return Reconciler{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run reconciles once per interval until ctx is cancelled.
+func (r Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := r.Check(ctx)
+			if err != nil {
+				r.logger.Errorf("failed to reconcile balances: %v", err)
+				continue
+			}
+			if len(report.Drift) > 0 {
+				body, _ := json.Marshal(report)
+				r.logger.Warnf("balance drift detected: %s", body)
+			}
+		}
+	}
+}
+
+func (r Reconciler) Check(ctx context.Context) (*ReconcileReport, error) {
+	drift, err := r.repo.FindDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if drift == nil {
+		drift = []entity.BalanceDrift{}
+	}
+
+	return &ReconcileReport{
+		CheckedAt: time.Now().UTC(),
+		Drift:     drift,
+		Negative:  []entity.BalanceDrift{},
+	}, nil
+}
+
+// Correct posts an adjustment entry for each drifted balance and records how many
+// were fixed. Balances that would have to go negative are reported instead.
+func (r Reconciler) Correct(ctx context.Context, report *ReconcileReport) error {
+	for _, drift := range report.Drift {
+		if drift.Expected < 0 {
+			report.Negative = append(report.Negative, drift)
+			r.logger.Warnf("expected balance of %s is %d, left for manual review", drift.Username, drift.Expected)
+			continue
+		}
+
+		corrected, err := r.repo.CorrectBalance(ctx, drift)
+		if err != nil {
+			return err
+		}
+		if corrected {
+			report.Corrected++
+		} else {
+			r.logger.Warnf("balance of %s changed during reconciliation, skipped", drift.Username)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"avito_test/pkg/logger"
+	"context"
+	"time"
+)
+
+type RevokedTokenRepository interface {
+	DeleteExpiredAccessTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RevokedTokenSweeper periodically deletes revocations of access tokens that
+// have expired anyway.
+type RevokedTokenSweeper struct {
+	repo     RevokedTokenRepository
+	interval time.Duration
+	logger   *logger.ApiLogger
+}
+
+func NewRevokedTokenSweeper(repo RevokedTokenRepository, interval time.Duration, logger *logger.ApiLogger) RevokedTokenSweeper {
+	return RevokedTokenSweeper{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run sweeps once per interval until ctx is cancelled.
+func (s RevokedTokenSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+func (s RevokedTokenSweeper) Sweep(ctx context.Context) {
+	deleted, err := s.repo.DeleteExpiredAccessTokens(ctx, time.Now())
+	if err != nil {
+		s.logger.Errorf("failed to sweep revoked access tokens: %v", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Infof("swept %d expired revoked access tokens", deleted)
+	}
+}
@@ -14,18 +14,11 @@ export let options = {
     },
 };
 
-const items = [
-    { id: 't-shirt', price: 80 },
-    { id: 'cup', price: 20 },
-    { id: 'book', price: 50 },
-    { id: 'pen', price: 10 },
-    { id: 'powerbank', price: 200 },
-    { id: 'hoody', price: 300 },
-    { id: 'umbrella', price: 200 },
-    { id: 'socks', price: 10 },
-    { id: 'wallet', price: 50 },
-    { id: 'pink-hoody', price: 500 },
-];
+export function setup() {
+    const res = http.get('http://localhost:8080/api/items');
+    const catalog = JSON.parse(res.body);
+    return { items: catalog.items.filter((item) => item.available && item.stock === undefined && !item.variants) };
+}
 
 const users = Array.from({ length: 10 }, (_, i) => `user_${i + 1}`);
 
@@ -45,7 +38,7 @@ function getAuthToken() {
     return null;
 }
 
-export default function () {
+export default function (data) {
     const token = getAuthToken();
     if (!token) {
         console.error('Failed to authenticate');
@@ -57,8 +50,8 @@ export default function () {
         'Authorization': `Bearer ${token}`,
     };
 
-    const randomItem = items[Math.floor(Math.random() * items.length)];
-    const buyRes = http.get(`http://localhost:8080/transactions/buy/${randomItem.id}`, { headers });
+    const randomItem = data.items[Math.floor(Math.random() * data.items.length)];
+    const buyRes = http.get(`http://localhost:8080/transactions/buy/${randomItem.type}`, { headers });
     check(buyRes, {
         'Buy request successful': (r) => r.status === 200,
         'Response time < 50ms': (r) => r.timings.duration < 50,
@@ -0,0 +1,2 @@
+-- Hashed passwords cannot be turned back into plaintext.
+SELECT 1;
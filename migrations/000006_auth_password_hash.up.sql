@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- Plaintext passwords are hashed with bcrypt in place; the service upgrades
+-- them to the configured algorithm on the next successful login.
+UPDATE auth
+SET password = crypt(password, gen_salt('bf', 10))
+WHERE password NOT LIKE '$argon2id$%'
+  AND password NOT LIKE '$2_$%';
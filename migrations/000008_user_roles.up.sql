@@ -0,0 +1,9 @@
+CREATE TABLE user_roles(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('user', 'admin', 'auditor')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'user' FROM users;
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT NOT NULL,
+    response BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys(created_at);
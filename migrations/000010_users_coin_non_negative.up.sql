@@ -0,0 +1,2 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_coin_check;
+ALTER TABLE users ADD CONSTRAINT users_coin_check CHECK (coin >= 0);
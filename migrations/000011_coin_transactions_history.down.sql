@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS coin_transactions_to_user_created_at_idx;
+DROP INDEX IF EXISTS coin_transactions_from_user_created_at_idx;
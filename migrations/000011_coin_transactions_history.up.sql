@@ -0,0 +1,2 @@
+CREATE INDEX coin_transactions_from_user_created_at_idx ON coin_transactions(from_user, created_at DESC, id DESC);
+CREATE INDEX coin_transactions_to_user_created_at_idx ON coin_transactions(to_user, created_at DESC, id DESC);
@@ -0,0 +1,75 @@
+CREATE TABLE ledger_accounts(
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('user', 'revenue', 'issuance')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User accounts share the user's id; the system accounts have fixed ids.
+INSERT INTO ledger_accounts (id, kind)
+VALUES
+    ('00000000-0000-0000-0000-000000000001', 'revenue'),
+    ('00000000-0000-0000-0000-000000000002', 'issuance');
+
+CREATE TABLE journal_entries(
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('opening_balance', 'grant', 'transfer', 'purchase')),
+    reference TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE postings(
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX postings_entry_id_idx ON postings(entry_id);
+CREATE INDEX postings_account_id_idx ON postings(account_id);
+
+CREATE FUNCTION ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger table % is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journal_entries_append_only
+    BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+CREATE TRIGGER postings_append_only
+    BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+-- Checked at commit, once all postings of the entry have been written.
+CREATE FUNCTION ledger_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    total BIGINT;
+BEGIN
+    SELECT SUM(amount) INTO total FROM postings WHERE entry_id = NEW.entry_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance: %', NEW.entry_id, total;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_entry_balanced();
+
+-- Open every existing account with its current balance, issued from the grants account.
+INSERT INTO ledger_accounts (id, kind)
+SELECT id, 'user' FROM users;
+
+WITH opening AS (
+    INSERT INTO journal_entries (kind, reference)
+    SELECT 'opening_balance', id::TEXT FROM users WHERE coin > 0
+    RETURNING id, reference
+)
+INSERT INTO postings (entry_id, account_id, amount)
+SELECT o.id, u.id, u.coin FROM opening o JOIN users u ON u.id::TEXT = o.reference
+UNION ALL
+SELECT o.id, '00000000-0000-0000-0000-000000000002', -u.coin FROM opening o JOIN users u ON u.id::TEXT = o.reference;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS orders;
+
+ALTER TABLE user_items ADD CONSTRAINT user_items_type_key UNIQUE (type);
@@ -0,0 +1,14 @@
+-- type was globally unique, so only one user could ever own a given item.
+ALTER TABLE user_items DROP CONSTRAINT IF EXISTS user_items_type_key;
+
+CREATE TABLE orders(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_type TEXT NOT NULL,
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    unit_price INT NOT NULL CHECK (unit_price >= 0),
+    journal_entry_id BIGINT REFERENCES journal_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX orders_user_id_created_at_idx ON orders(user_id, created_at DESC, id DESC);
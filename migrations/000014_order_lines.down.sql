@@ -0,0 +1,20 @@
+ALTER TABLE orders
+    ADD COLUMN item_type TEXT,
+    ADD COLUMN quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    ADD COLUMN unit_price INT NOT NULL DEFAULT 0 CHECK (unit_price >= 0);
+
+-- Multi-line orders keep only their first line.
+UPDATE orders o
+SET item_type = l.item_type, quantity = l.quantity, unit_price = l.unit_price
+FROM (
+    SELECT DISTINCT ON (order_id) order_id, item_type, quantity, unit_price
+    FROM order_lines
+    ORDER BY order_id, id
+) l
+WHERE l.order_id = o.id;
+
+DELETE FROM orders WHERE item_type IS NULL;
+ALTER TABLE orders ALTER COLUMN item_type SET NOT NULL;
+ALTER TABLE orders DROP COLUMN total;
+
+DROP TABLE IF EXISTS order_lines;
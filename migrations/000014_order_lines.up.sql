@@ -0,0 +1,19 @@
+CREATE TABLE order_lines(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    item_type TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_price INT NOT NULL CHECK (unit_price >= 0),
+    UNIQUE (order_id, item_type)
+);
+
+INSERT INTO order_lines (order_id, item_type, quantity, unit_price)
+SELECT id, item_type, quantity, unit_price FROM orders;
+
+ALTER TABLE orders ADD COLUMN total INT NOT NULL DEFAULT 0 CHECK (total >= 0);
+UPDATE orders SET total = quantity * unit_price;
+
+ALTER TABLE orders
+    DROP COLUMN item_type,
+    DROP COLUMN quantity,
+    DROP COLUMN unit_price;
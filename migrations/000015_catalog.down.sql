@@ -0,0 +1,7 @@
+ALTER TABLE items
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS available,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS description,
+    DROP CONSTRAINT IF EXISTS items_price_check,
+    ALTER COLUMN price DROP NOT NULL;
@@ -0,0 +1,20 @@
+UPDATE items SET price = 0 WHERE price IS NULL;
+
+ALTER TABLE items
+    ALTER COLUMN price SET NOT NULL,
+    ADD CONSTRAINT items_price_check CHECK (price >= 0),
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN category TEXT NOT NULL DEFAULT 'other',
+    ADD COLUMN available BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE items SET category = 'apparel', description = 'Футболка с логотипом' WHERE type = 't-shirt';
+UPDATE items SET category = 'drinkware', description = 'Кружка с логотипом' WHERE type = 'cup';
+UPDATE items SET category = 'stationery', description = 'Книга' WHERE type = 'book';
+UPDATE items SET category = 'stationery', description = 'Ручка с логотипом' WHERE type = 'pen';
+UPDATE items SET category = 'electronics', description = 'Внешний аккумулятор' WHERE type = 'powerbank';
+UPDATE items SET category = 'apparel', description = 'Худи с логотипом' WHERE type = 'hoody';
+UPDATE items SET category = 'accessories', description = 'Зонт с логотипом' WHERE type = 'umbrella';
+UPDATE items SET category = 'apparel', description = 'Носки с логотипом' WHERE type = 'socks';
+UPDATE items SET category = 'accessories', description = 'Кошелёк' WHERE type = 'wallet';
+UPDATE items SET category = 'apparel', description = 'Розовое худи' WHERE type = 'pink-hoody';
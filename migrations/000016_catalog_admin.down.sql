@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS item_price_history;
+
+ALTER TABLE items DROP COLUMN IF EXISTS retired_at;
@@ -0,0 +1,18 @@
+ALTER TABLE items ADD COLUMN retired_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE item_price_history(
+    id BIGSERIAL PRIMARY KEY,
+    item_id UUID NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    change TEXT NOT NULL CHECK (change IN ('create', 'update', 'retire')),
+    price INT NOT NULL,
+    description TEXT NOT NULL,
+    category TEXT NOT NULL,
+    available BOOLEAN NOT NULL,
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX item_price_history_item_id_idx ON item_price_history(item_id, changed_at DESC, id DESC);
+
+INSERT INTO item_price_history (item_id, change, price, description, category, available)
+SELECT id, 'create', price, description, category, available FROM items;
@@ -0,0 +1,11 @@
+DELETE FROM item_price_history WHERE change = 'restock';
+
+ALTER TABLE item_price_history
+    DROP CONSTRAINT item_price_history_change_check,
+    ADD CONSTRAINT item_price_history_change_check CHECK (change IN ('create', 'update', 'retire')),
+    DROP COLUMN IF EXISTS low_stock_threshold,
+    DROP COLUMN IF EXISTS stock;
+
+ALTER TABLE items
+    DROP COLUMN IF EXISTS low_stock_threshold,
+    DROP COLUMN IF EXISTS stock;
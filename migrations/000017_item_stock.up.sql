@@ -0,0 +1,9 @@
+ALTER TABLE items
+    ADD COLUMN stock INT CONSTRAINT items_stock_check CHECK (stock >= 0),
+    ADD COLUMN low_stock_threshold INT CONSTRAINT items_low_stock_threshold_check CHECK (low_stock_threshold >= 0);
+
+ALTER TABLE item_price_history
+    ADD COLUMN stock INT,
+    ADD COLUMN low_stock_threshold INT,
+    DROP CONSTRAINT item_price_history_change_check,
+    ADD CONSTRAINT item_price_history_change_check CHECK (change IN ('create', 'update', 'retire', 'restock'));
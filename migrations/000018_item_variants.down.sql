@@ -0,0 +1,33 @@
+-- Fold the per-variant quantities back into one row per item.
+INSERT INTO user_items (user_id, type, variant, quantity)
+SELECT user_id, type, NULL, SUM(quantity) FROM user_items WHERE variant IS NOT NULL GROUP BY user_id, type
+ON CONFLICT (user_id, type, variant) DO UPDATE SET quantity = user_items.quantity + EXCLUDED.quantity;
+
+DELETE FROM user_items WHERE variant IS NOT NULL;
+
+ALTER TABLE user_items
+    DROP CONSTRAINT user_items_user_id_type_variant_key,
+    ADD CONSTRAINT user_items_user_id_type_key UNIQUE (user_id, type),
+    DROP COLUMN IF EXISTS variant;
+
+ALTER TABLE item_price_history DROP COLUMN IF EXISTS variant;
+
+-- Fold the lines of an order that bought several variants of one item into its
+-- first line. The unit price becomes their average; orders.total keeps the exact sum.
+WITH folded AS (
+    SELECT MIN(id) AS id, SUM(quantity) AS quantity, SUM(quantity * unit_price) / SUM(quantity) AS unit_price
+    FROM order_lines
+    GROUP BY order_id, item_type
+    HAVING COUNT(*) > 1
+)
+UPDATE order_lines l SET quantity = f.quantity, unit_price = f.unit_price FROM folded f WHERE l.id = f.id;
+
+DELETE FROM order_lines l USING order_lines k
+WHERE l.order_id = k.order_id AND l.item_type = k.item_type AND l.id > k.id;
+
+ALTER TABLE order_lines
+    DROP CONSTRAINT order_lines_order_id_item_type_variant_key,
+    ADD CONSTRAINT order_lines_order_id_item_type_key UNIQUE (order_id, item_type),
+    DROP COLUMN IF EXISTS variant;
+
+DROP TABLE IF EXISTS item_variants;
@@ -0,0 +1,26 @@
+CREATE TABLE item_variants(
+    id UUID PRIMARY KEY,
+    item_id UUID NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    sku TEXT NOT NULL UNIQUE,
+    size TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    price INT CONSTRAINT item_variants_price_check CHECK (price >= 0),
+    stock INT CONSTRAINT item_variants_stock_check CHECK (stock >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX item_variants_item_id_idx ON item_variants(item_id);
+
+-- A cart may hold several variants of one item, each on its own line.
+ALTER TABLE order_lines
+    ADD COLUMN variant TEXT,
+    DROP CONSTRAINT order_lines_order_id_item_type_key,
+    ADD CONSTRAINT order_lines_order_id_item_type_variant_key UNIQUE NULLS NOT DISTINCT (order_id, item_type, variant);
+
+ALTER TABLE item_price_history ADD COLUMN variant TEXT;
+
+-- Items without variants keep a NULL variant, and NULLS NOT DISTINCT keeps them to one row per user.
+ALTER TABLE user_items
+    ADD COLUMN variant TEXT,
+    DROP CONSTRAINT user_items_user_id_type_key,
+    ADD CONSTRAINT user_items_user_id_type_variant_key UNIQUE NULLS NOT DISTINCT (user_id, type, variant);
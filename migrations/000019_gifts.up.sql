@@ -0,0 +1,17 @@
+CREATE TABLE gifts(
+    id BIGSERIAL PRIMARY KEY,
+    from_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_type TEXT NOT NULL,
+    variant TEXT,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    source TEXT NOT NULL CHECK (source IN ('purchase', 'inventory')),
+    order_id BIGINT REFERENCES orders(id),
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_user <> to_user),
+    CHECK ((source = 'purchase') = (order_id IS NOT NULL))
+);
+
+CREATE INDEX gifts_from_user_created_at_idx ON gifts(from_user, created_at DESC, id DESC);
+CREATE INDEX gifts_to_user_created_at_idx ON gifts(to_user, created_at DESC, id DESC);
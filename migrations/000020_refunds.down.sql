@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS refunds;
+
+ALTER TABLE order_lines
+    DROP CONSTRAINT IF EXISTS order_lines_returned_check,
+    DROP COLUMN IF EXISTS returned;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS picked_up_at;
+
+-- The ledger is append-only, so refund entries already posted have to stay.
+ALTER TABLE journal_entries
+    DROP CONSTRAINT journal_entries_kind_check,
+    ADD CONSTRAINT journal_entries_kind_check CHECK (kind IN ('opening_balance', 'grant', 'transfer', 'purchase')) NOT VALID;
@@ -0,0 +1,25 @@
+ALTER TABLE journal_entries
+    DROP CONSTRAINT journal_entries_kind_check,
+    ADD CONSTRAINT journal_entries_kind_check CHECK (kind IN ('opening_balance', 'grant', 'transfer', 'purchase', 'refund'));
+
+-- Items handed out at the office can no longer be returned.
+ALTER TABLE orders ADD COLUMN picked_up_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE order_lines
+    ADD COLUMN returned INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT order_lines_returned_check CHECK (returned >= 0 AND returned <= quantity);
+
+CREATE TABLE refunds(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_type TEXT NOT NULL,
+    variant TEXT,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount INT NOT NULL CHECK (amount >= 0),
+    journal_entry_id BIGINT REFERENCES journal_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refunds_user_id_created_at_idx ON refunds(user_id, created_at DESC, id DESC);
+CREATE INDEX refunds_order_id_idx ON refunds(order_id);
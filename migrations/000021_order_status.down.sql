@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS orders_status_created_at_idx;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS status_changed_by,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,17 @@
+-- Orders placed before the workflow existed never went through it, so they are
+-- backfilled as picked up, or as cancelled when every line was returned. Only new
+-- orders start out as created.
+ALTER TABLE orders
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'picked_up' CHECK (status IN ('created', 'ready', 'picked_up', 'cancelled')),
+    ADD COLUMN status_changed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN status_changed_by UUID;
+
+UPDATE orders o SET status = 'cancelled'
+WHERE picked_up_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM order_lines l WHERE l.order_id = o.id AND l.returned < l.quantity);
+
+UPDATE orders SET status_changed_at = picked_up_at WHERE picked_up_at IS NOT NULL;
+
+ALTER TABLE orders ALTER COLUMN status SET DEFAULT 'created';
+
+CREATE INDEX orders_status_created_at_idx ON orders(status, created_at DESC, id DESC);
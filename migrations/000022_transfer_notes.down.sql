@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS coin_transactions_kudos_idx;
+
+ALTER TABLE coin_transactions
+    DROP COLUMN IF EXISTS private,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS message;
@@ -0,0 +1,8 @@
+ALTER TABLE coin_transactions
+    ADD COLUMN message TEXT NOT NULL DEFAULT '',
+    ADD COLUMN category TEXT,
+    ADD COLUMN private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Serves the public kudos feed: transfers that carry a note and were not sent privately.
+CREATE INDEX coin_transactions_kudos_idx ON coin_transactions(created_at DESC, id DESC)
+    WHERE NOT private AND (category IS NOT NULL OR message <> '');
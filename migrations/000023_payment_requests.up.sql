@@ -0,0 +1,18 @@
+-- A pending request whose expires_at has passed is reported as expired; it is
+-- never rewritten, so no sweeper is needed.
+CREATE TABLE payment_requests(
+    id BIGSERIAL PRIMARY KEY,
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    message TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'declined')),
+    transfer_id INT REFERENCES coin_transactions(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (requester_id <> payer_id)
+);
+
+CREATE INDEX payment_requests_requester_id_created_at_idx ON payment_requests(requester_id, created_at DESC, id DESC);
+CREATE INDEX payment_requests_payer_id_pending_idx ON payment_requests(payer_id, created_at DESC, id DESC) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+-- The ledger is append-only, so adjustment entries already posted have to stay.
+ALTER TABLE journal_entries
+    DROP CONSTRAINT journal_entries_kind_check,
+    ADD CONSTRAINT journal_entries_kind_check CHECK (kind IN ('opening_balance', 'grant', 'transfer', 'purchase', 'refund')) NOT VALID;
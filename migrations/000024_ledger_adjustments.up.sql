@@ -0,0 +1,4 @@
+-- Reconciliation corrects balances with adjustment entries instead of rewriting users.coin.
+ALTER TABLE journal_entries
+    DROP CONSTRAINT journal_entries_kind_check,
+    ADD CONSTRAINT journal_entries_kind_check CHECK (kind IN ('opening_balance', 'grant', 'transfer', 'purchase', 'refund', 'adjustment'));
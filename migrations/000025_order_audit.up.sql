@@ -0,0 +1,10 @@
+-- Written by the service in the transaction that placed the order.
+CREATE TABLE order_audit(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('buy', 'checkout')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_audit_order_id_idx ON order_audit(order_id);
@@ -0,0 +1,128 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	_defaultSaltLength = 16
+	_defaultKeyLength  = 32
+	_argon2idPrefix    = "$argon2id$"
+)
+
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2id encodes hashes in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) Argon2id {
+	return Argon2id{
+		params: params,
+	}
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.WithMessage(err, "failed to generate salt")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		_argon2idPrefix,
+		argon2.Version,
+		a.params.Memory,
+		a.params.Iterations,
+		a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a Argon2id) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	// nolint: gosec
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (a Argon2id) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, _argon2idPrefix)
+}
+
+func (a Argon2id) Outdated(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != a.params.Memory ||
+		params.Iterations != a.params.Iterations ||
+		params.Parallelism != a.params.Parallelism ||
+		len(salt) != int(a.params.SaltLength) ||
+		len(key) != int(a.params.KeyLength)
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, errors.WithMessage(err, "failed to parse argon2id version")
+	}
+	if version != argon2.Version {
+		return params, nil, nil, errors.Errorf("unsupported argon2id version %d", version)
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, errors.WithMessage(err, "failed to parse argon2id parameters")
+	}
+	// argon2.IDKey panics on zero iterations or parallelism.
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, errors.New("argon2id parameters must be positive")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errors.WithMessage(err, "failed to decode argon2id salt")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, errors.WithMessage(err, "failed to decode argon2id key")
+	}
+
+	// An empty key would compare equal to the empty key derived for any password.
+	if len(salt) == 0 || len(key) == 0 {
+		return params, nil, nil, errors.New("argon2id salt and key must not be empty")
+	}
+
+	return params, salt, key, nil
+}
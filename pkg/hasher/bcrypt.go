@@ -0,0 +1,47 @@
+package hasher
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+type Bcrypt struct {
+	cost int
+}
+
+func NewBcrypt(cost int) Bcrypt {
+	return Bcrypt{
+		cost: cost,
+	}
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to generate bcrypt hash")
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to compare bcrypt hash")
+	}
+	return true, nil
+}
+
+func (b Bcrypt) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
@@ -0,0 +1,78 @@
+package hasher
+
+import (
+	"avito_test/internal/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Algorithm is a single password hashing scheme with its own encoded format.
+type Algorithm interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	// Matches reports whether the encoded hash was produced by this algorithm.
+	Matches(encoded string) bool
+	// Outdated reports whether the encoded hash uses parameters other than the configured ones.
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes produced by any supported one.
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+func NewHasher(cfg *config.Config) (*Hasher, error) {
+	argon := NewArgon2id(Argon2idParams{
+		Memory:      cfg.Password.Argon2Memory,
+		Iterations:  cfg.Password.Argon2Iterations,
+		Parallelism: cfg.Password.Argon2Parallelism,
+		SaltLength:  _defaultSaltLength,
+		KeyLength:   _defaultKeyLength,
+	})
+	bcrypt := NewBcrypt(cfg.Password.BcryptCost)
+
+	h := &Hasher{
+		algorithms: []Algorithm{argon, bcrypt},
+	}
+
+	switch cfg.Password.Algorithm {
+	case AlgorithmArgon2id, "":
+		h.current = argon
+	case AlgorithmBcrypt:
+		h.current = bcrypt
+	default:
+		return nil, errors.Errorf("unsupported password hash algorithm %q", cfg.Password.Algorithm)
+	}
+
+	return h, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(encoded, password string) (bool, error) {
+	for _, algorithm := range h.algorithms {
+		if algorithm.Matches(encoded) {
+			return algorithm.Verify(encoded, password)
+		}
+	}
+	return false, ErrUnknownHashFormat
+}
+
+// NeedsRehash reports whether the encoded hash should be replaced by a fresh
+// one made with the configured algorithm and parameters.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if !h.current.Matches(encoded) {
+		return true
+	}
+	return h.current.Outdated(encoded)
+}
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"avito_test/internal/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewMockConfig(algorithm string) *config.Config {
+	cfg := &config.Config{}
+	cfg.Password.Algorithm = algorithm
+	cfg.Password.BcryptCost = 4
+	cfg.Password.Argon2Memory = 1024
+	cfg.Password.Argon2Iterations = 1
+	cfg.Password.Argon2Parallelism = 1
+	return cfg
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h, err := NewHasher(NewMockConfig(algorithm))
+			require.NoError(t, err)
+
+			encoded, err := h.Hash("ValidPass123!")
+			require.NoError(t, err)
+			assert.NotEqual(t, "ValidPass123!", encoded, "Hash should not store the plaintext password")
+
+			ok, err := h.Verify(encoded, "ValidPass123!")
+			assert.NoError(t, err)
+			assert.True(t, ok, "Verify should accept the correct password")
+
+			ok, err = h.Verify(encoded, "wrongPass")
+			assert.NoError(t, err)
+			assert.False(t, ok, "Verify should reject a wrong password")
+
+			assert.False(t, h.NeedsRehash(encoded), "Fresh hash should not need a rehash")
+		})
+	}
+}
+
+func TestArgon2idEncodedFormat(t *testing.T) {
+	h, err := NewHasher(NewMockConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("ValidPass123!")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"), "Encoded hash should carry its parameters")
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHasher, err := NewHasher(NewMockConfig(AlgorithmBcrypt))
+	require.NoError(t, err)
+	bcryptHash, err := bcryptHasher.Hash("ValidPass123!")
+	require.NoError(t, err)
+
+	argonHasher, err := NewHasher(NewMockConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	ok, err := argonHasher.Verify(bcryptHash, "ValidPass123!")
+	assert.NoError(t, err)
+	assert.True(t, ok, "Verify should accept hashes of a non-default algorithm")
+	assert.True(t, argonHasher.NeedsRehash(bcryptHash), "Hash of another algorithm should need a rehash")
+
+	argonHash, err := argonHasher.Hash("ValidPass123!")
+	require.NoError(t, err)
+
+	strongerCfg := NewMockConfig(AlgorithmArgon2id)
+	strongerCfg.Password.Argon2Iterations = 2
+	strongerHasher, err := NewHasher(strongerCfg)
+	require.NoError(t, err)
+	assert.True(t, strongerHasher.NeedsRehash(argonHash), "Hash with outdated parameters should need a rehash")
+}
+
+func TestVerifyUnknownFormat(t *testing.T) {
+	h, err := NewHasher(NewMockConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	_, err = h.Verify("plaintext", "plaintext")
+	assert.ErrorIs(t, err, ErrUnknownHashFormat)
+}
+
+func TestVerifyMalformedArgon2id(t *testing.T) {
+	h, err := NewHasher(NewMockConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	for name, encoded := range map[string]string{
+		"Empty Key":        "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+		"Empty Salt":       "$argon2id$v=19$m=1024,t=1,p=1$$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+		"Zero Iterations":  "$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+		"Zero Parallelism": "$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+		"Zero Memory":      "$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, err := h.Verify(encoded, "")
+			assert.Error(t, err)
+			assert.False(t, ok, "Malformed hash must not verify")
+			assert.True(t, h.NeedsRehash(encoded), "Malformed hash should need a rehash")
+		})
+	}
+}
+
+func TestNewHasher_UnsupportedAlgorithm(t *testing.T) {
+	_, err := NewHasher(NewMockConfig("md5"))
+	assert.Error(t, err)
+}
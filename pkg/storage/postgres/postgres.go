@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"avito_test/internal/config"
+	"avito_test/pkg/logger"
 	"fmt"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
@@ -9,7 +10,6 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
-	"log"
 	"time"
 )
 
@@ -33,10 +33,11 @@ type Postgres interface {
 }
 
 type Pool struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	logger *logger.ApiLogger
 }
 
-func InitPsqlDB(c *config.Config) (Postgres, error) { // nolint: ireturn
+func InitPsqlDB(c *config.Config, logger *logger.ApiLogger) (Postgres, error) { // nolint: ireturn
 	connectionUrl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Postgres.Host,
 		c.Postgres.Port,
@@ -57,17 +58,27 @@ func InitPsqlDB(c *config.Config) (Postgres, error) { // nolint: ireturn
 			break
 		}
 
-		log.Printf("ATTEMPT %d TO CONNECT TO POSTGRES BY URL %s FAILED: %s\n", connectionAttempts, connectionUrl, err.Error())
+		logger.Warnf("ATTEMPT %d TO CONNECT TO POSTGRES BY URL %s FAILED: %s", connectionAttempts, connectionUrl, err.Error())
 		connectionAttempts--
 		time.Sleep(_defaultConnectionTimeout)
 	}
 
 	if result == nil {
-		log.Printf("POSTGRES CONNECTION(%s) ERROR: %s\n", connectionUrl, err.Error())
+		logger.Errorf("POSTGRES CONNECTION(%s) ERROR: %s", connectionUrl, err.Error())
 		return nil, errors.WithMessage(err, "failed to initialize PostgreSQL connection")
 	}
 
-	return &Pool{db: result}, nil
+	return &Pool{db: result, logger: logger}, nil
+}
+
+// NewPool wraps an already configured pgx pool, e.g. one opened by tests against a scratch database.
+func NewPool(db *pgxpool.Pool) *Pool {
+	return &Pool{db: db}
+}
+
+// Logger is the logger ExecTx reports retries to; it is nil for pools built with NewPool.
+func (p *Pool) Logger() *logger.ApiLogger {
+	return p.logger
 }
 
 func (p *Pool) Stats() *pgxpool.Stat {
@@ -82,6 +93,14 @@ func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) { // nolint: ireturn
 	return tx, nil
 }
 
+func (p *Pool) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) { // nolint: ireturn
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to begin transaction")
+	}
+	return tx, nil
+}
+
 func (p *Pool) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) { // nolint: ireturn
 	rows, err := p.db.Query(ctx, query, args...)
 	if err != nil {
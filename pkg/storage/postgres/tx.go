@@ -1,11 +1,15 @@
 package postgres
 
 import (
+	"avito_test/pkg/logger"
 	"context"
+	"fmt"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pkg/errors"
+	"math/rand"
+	"time"
 )
 
 type Tx struct {
@@ -16,10 +20,114 @@ type TxReq func(tx Tx) error
 
 type TxRunner interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
 }
 
-func ExecTx(ctx context.Context, runner TxRunner, req TxReq) error {
-	pgxTx, err := runner.Begin(ctx)
+// loggingRunner is implemented by runners that carry the application logger, such as a Pool
+// opened by InitPsqlDB. ExecTx reports retries through it and stays silent otherwise.
+type loggingRunner interface {
+	Logger() *logger.ApiLogger
+}
+
+type txConfig struct {
+	opts             pgx.TxOptions
+	statementTimeout time.Duration
+}
+
+type TxOption func(cfg *txConfig)
+
+// WithIsolation sets the isolation level; the server default (READ COMMITTED) is used otherwise.
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(cfg *txConfig) {
+		cfg.opts.IsoLevel = level
+	}
+}
+
+func ReadOnly() TxOption {
+	return func(cfg *txConfig) {
+		cfg.opts.AccessMode = pgx.ReadOnly
+	}
+}
+
+// Deferrable only has an effect on SERIALIZABLE READ ONLY transactions.
+func Deferrable() TxOption {
+	return func(cfg *txConfig) {
+		cfg.opts.DeferrableMode = pgx.Deferrable
+	}
+}
+
+// WithStatementTimeout limits every statement of the transaction via SET LOCAL statement_timeout.
+func WithStatementTimeout(timeout time.Duration) TxOption {
+	return func(cfg *txConfig) {
+		cfg.statementTimeout = timeout
+	}
+}
+
+const (
+	_maxTxAttempts     = 5
+	_txRetryBaseDelay  = 10 * time.Millisecond
+	_txRetryMaxDelay   = 200 * time.Millisecond
+	serializationError = "40001"
+	deadlockDetected   = "40P01"
+)
+
+// ExecTx runs req in a transaction. Serialization failures and deadlocks are retried
+// with jittered exponential backoff, so req must be safe to run more than once.
+// When ctx already carries a transaction, req runs in a savepoint of it and is not
+// retried on its own: a failed outer transaction is retried as a whole by its owner.
+// A nested call cannot change the isolation level, access mode or deferrability the
+// outer transaction began with, so those options are ignored; only a statement
+// timeout is applied, and it stays in effect until the outer transaction ends.
+func ExecTx(ctx context.Context, runner TxRunner, req TxReq, opts ...TxOption) error {
+	var cfg txConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if outer, ok := TxFromContext(ctx); ok {
+		return execSavepoint(ctx, outer, cfg, req)
+	}
+
+	var log *logger.ApiLogger
+	if lr, ok := runner.(loggingRunner); ok {
+		log = lr.Logger()
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = execTx(ctx, runner, cfg, req)
+		if err == nil {
+			if attempt > 1 && log != nil {
+				log.Infof("transaction succeeded after %d retries", attempt-1)
+			}
+			return nil
+		}
+
+		if !isRetryable(err) || attempt == _maxTxAttempts {
+			break
+		}
+
+		delay := retryDelay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+
+		if log != nil {
+			log.Warnf("transaction attempt %d/%d failed, retrying in %v: %v", attempt, _maxTxAttempts, delay, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.WithMessage(err, ctx.Err().Error())
+		case <-time.After(delay):
+		}
+	}
+
+	return err
+}
+
+func execTx(ctx context.Context, runner TxRunner, cfg txConfig, req TxReq) error {
+	pgxTx, err := runner.BeginTx(ctx, cfg.opts)
 	if err != nil {
 		return errors.WithMessage(err, "failed to begin transaction")
 	}
@@ -28,6 +136,11 @@ func ExecTx(ctx context.Context, runner TxRunner, req TxReq) error {
 		db: pgxTx,
 	}
 
+	if err = setStatementTimeout(ctx, tx, cfg); err != nil {
+		_ = tx.db.Rollback(ctx)
+		return err
+	}
+
 	if err = req(tx); err != nil {
 		_ = tx.db.Rollback(ctx)
 		return errors.WithMessage(err, "transaction execution failed")
@@ -40,6 +153,61 @@ func ExecTx(ctx context.Context, runner TxRunner, req TxReq) error {
 	return nil
 }
 
+func execSavepoint(ctx context.Context, outer Tx, cfg txConfig, req TxReq) error {
+	savepoint, err := outer.Begin(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create savepoint")
+	}
+
+	tx := Tx{
+		db: savepoint,
+	}
+
+	if err = setStatementTimeout(ctx, tx, cfg); err != nil {
+		_ = tx.db.Rollback(ctx)
+		return err
+	}
+
+	if err = req(tx); err != nil {
+		_ = tx.db.Rollback(ctx)
+		return errors.WithMessage(err, "savepoint execution failed")
+	}
+
+	if err = tx.db.Commit(ctx); err != nil {
+		return errors.WithMessage(err, "failed to release savepoint")
+	}
+
+	return nil
+}
+
+func setStatementTimeout(ctx context.Context, tx Tx, cfg txConfig) error {
+	if cfg.statementTimeout <= 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("SET LOCAL statement_timeout = %d", cfg.statementTimeout.Milliseconds())
+	if _, err := tx.db.Exec(ctx, query); err != nil {
+		return errors.WithMessage(err, "failed to set statement timeout")
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationError || pgErr.Code == deadlockDetected
+}
+
+func retryDelay(attempt int) time.Duration {
+	delay := _txRetryBaseDelay << (attempt - 1)
+	if delay > _txRetryMaxDelay {
+		delay = _txRetryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
 func (p Tx) Begin(ctx context.Context) (pgx.Tx, error) {
 	tx, err := p.db.Begin(ctx)
 	if err != nil {
@@ -48,6 +216,11 @@ func (p Tx) Begin(ctx context.Context) (pgx.Tx, error) {
 	return tx, nil
 }
 
+// BeginTx starts a savepoint; isolation and access mode are inherited from the outer transaction.
+func (p Tx) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return p.Begin(ctx)
+}
+
 func (p Tx) Commit(ctx context.Context) error {
 	if err := p.db.Commit(ctx); err != nil {
 		return errors.WithMessage(err, "failed to commit transaction")
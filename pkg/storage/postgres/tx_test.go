@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"avito_test/internal/config"
+	"avito_test/pkg/logger"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, want: true},
+		{name: "wrapped deadlock", err: errors.WithMessage(&pgconn.PgError{Code: "40P01"}, "failed to lock accounts"), want: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "plain error", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := retryDelay(attempt)
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, _txRetryMaxDelay)
+	}
+}
+
+type recordingRunner struct {
+	opts pgx.TxOptions
+}
+
+func (r *recordingRunner) Begin(ctx context.Context) (pgx.Tx, error) { // nolint: ireturn
+	return r.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (r *recordingRunner) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) { // nolint: ireturn
+	r.opts = opts
+	return nil, errors.New("not connected")
+}
+
+func TestExecTx_Options(t *testing.T) {
+	runner := &recordingRunner{}
+
+	err := ExecTx(context.Background(), runner, func(tx Tx) error { return nil },
+		WithIsolation(pgx.Serializable), ReadOnly(), Deferrable())
+	require.Error(t, err)
+	require.Equal(t, pgx.TxOptions{
+		IsoLevel:       pgx.Serializable,
+		AccessMode:     pgx.ReadOnly,
+		DeferrableMode: pgx.Deferrable,
+	}, runner.opts)
+
+	err = ExecTx(context.Background(), runner, func(tx Tx) error { return nil })
+	require.Error(t, err)
+	require.Equal(t, pgx.TxOptions{}, runner.opts)
+}
+
+// conflictingRunner fails every BeginTx with a serialization failure.
+type conflictingRunner struct {
+	attempts int
+	logger   *logger.ApiLogger
+}
+
+func (r *conflictingRunner) Begin(ctx context.Context) (pgx.Tx, error) { // nolint: ireturn
+	return r.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (r *conflictingRunner) BeginTx(_ context.Context, _ pgx.TxOptions) (pgx.Tx, error) { // nolint: ireturn
+	r.attempts++
+	return nil, &pgconn.PgError{Code: serializationError}
+}
+
+func (r *conflictingRunner) Logger() *logger.ApiLogger {
+	return r.logger
+}
+
+func TestExecTx_Retries(t *testing.T) {
+	apiLogger := logger.NewApiLogger(&config.Config{})
+	require.NoError(t, apiLogger.InitLogger())
+
+	tests := []struct {
+		name   string
+		logger *logger.ApiLogger
+	}{
+		{name: "with logger", logger: apiLogger},
+		{name: "without logger"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &conflictingRunner{logger: tt.logger}
+
+			err := ExecTx(context.Background(), runner, func(tx Tx) error { return nil })
+			require.True(t, isRetryable(err))
+			require.Equal(t, _maxTxAttempts, runner.attempts)
+		})
+	}
+}
+
+func TestTxFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := TxFromContext(ctx)
+	require.False(t, ok)
+
+	pool := &Pool{}
+	require.Same(t, pool, Conn(ctx, pool))
+
+	tx := Tx{}
+	ctx = WithTx(ctx, tx)
+	got, ok := TxFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, tx, got)
+	require.Equal(t, tx, Conn(ctx, pool))
+}
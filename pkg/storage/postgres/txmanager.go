@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type txKey struct{}
+
+// Querier is the statement API shared by the pool and an open transaction.
+type Querier interface {
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+	Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// TxManager runs a unit of work in one transaction and exposes it to repositories through ctx.
+type TxManager struct {
+	db TxRunner
+}
+
+func NewTxManager(db TxRunner) TxManager {
+	return TxManager{
+		db: db,
+	}
+}
+
+// Do runs fn in a transaction started with opts and retries it as a whole on serialization
+// failures and deadlocks. Repository calls made with the ctx passed to fn join that
+// transaction, and the isolation level and access mode they ask ExecTx for are ignored in
+// favour of opts. If ctx already carries a transaction, fn runs in a savepoint instead.
+func (m TxManager) Do(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	return ExecTx(ctx, m.db, func(tx Tx) error {
+		return fn(WithTx(ctx, tx))
+	}, opts...)
+}
+
+func WithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(Tx)
+	return tx, ok
+}
+
+// Conn returns the transaction carried by ctx, or db when there is none.
+func Conn(ctx context.Context, db Querier) Querier { // nolint: ireturn
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}